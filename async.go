@@ -0,0 +1,268 @@
+package unreader
+
+import (
+	"io"
+	"sync"
+)
+
+// AsyncUnreader fills an Unreader's buffer from a producer goroutine, so
+// that reads and peeks are served from the buffer while the underlying
+// reader is drained in the background. The producer stops once high bytes
+// are buffered after the cursor and resumes when they drop to low, or when
+// a read needs more. Available signals when new bytes arrive, for callers
+// that select on several sources instead of blocking in a read.
+type AsyncUnreader struct {
+	u   *Unreader
+	src io.Reader // the Unreader's reader, drained by the producer
+
+	mu      sync.Mutex
+	cond    *sync.Cond // signaled when the buffer or state changes
+	low     int
+	high    int
+	need    int           // bytes a waiting read needs buffered, or 0
+	paused  bool          // high was reached and low hasn't been since
+	pending []byte        // bytes read by the producer that didn't fit yet
+	err     error         // error that ended the producer
+	quit    bool          // Stop or Close was called
+	running bool          // the producer hasn't exited
+	avail   chan struct{} // receives when bytes arrive or the producer ends
+	done    chan struct{} // closed when the producer exits
+}
+
+// NewAsyncUnreader starts a producer filling u's buffer, with the given
+// low and high watermarks of bytes buffered after the cursor. A high of
+// zero or less, or more than the buffer size, means the buffer size, and
+// low is kept below high. u must not be used directly afterwards.
+func NewAsyncUnreader(u *Unreader, low, high int) *AsyncUnreader {
+	if high <= 0 || int64(high) > u.cb.Size() {
+		high = int(u.cb.Size())
+	}
+	a := &AsyncUnreader{
+		u:       u,
+		src:     u.rd,
+		low:     max(0, min(low, high-1)),
+		high:    high,
+		running: true,
+		avail:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+	u.rd = asyncSource{a}
+	go a.run()
+	return a
+}
+
+// asyncSource stands in for the underlying reader of an AsyncUnreader's
+// Unreader, which only the producer reads.
+type asyncSource struct {
+	a *AsyncUnreader
+}
+
+func (s asyncSource) Read([]byte) (int, error) {
+	if s.a.running {
+		return 0, ErrWouldBlock
+	}
+	return 0, s.a.err
+}
+
+func (a *AsyncUnreader) run() {
+	p := make([]byte, fillSize)
+	a.mu.Lock()
+	defer func() {
+		a.running = false
+		a.notify()
+		a.cond.Broadcast()
+		a.mu.Unlock()
+		close(a.done)
+	}()
+	for !a.quit {
+		for len(a.pending) > 0 && !a.quit {
+			free := a.free()
+			if free == 0 {
+				a.cond.Wait()
+				continue
+			}
+			k := min(len(a.pending), free)
+			a.u.record(a.pending[:k])
+			a.pending = a.pending[k:]
+			a.notify()
+			a.cond.Broadcast()
+		}
+		if a.quit || a.err != nil {
+			return
+		}
+		want := a.want()
+		if want == 0 {
+			a.cond.Wait()
+			continue
+		}
+		a.mu.Unlock()
+		n, err := a.src.Read(p[:min(want, len(p))])
+		a.mu.Lock()
+		a.pending = p[:n]
+		a.err = err
+	}
+}
+
+// want returns how many bytes the producer should read next, or 0 to
+// wait, applying the watermarks.
+func (a *AsyncUnreader) want() int {
+	buffered := int(a.u.Buffered())
+	target := max(a.high, a.need)
+	if a.paused && buffered > a.low && buffered >= a.need {
+		return 0
+	}
+	a.paused = buffered >= target
+	if a.paused {
+		return 0
+	}
+	return min(target-buffered, a.free())
+}
+
+// free returns how many bytes can be recorded without evicting the
+// cursor or pinned bytes.
+func (a *AsyncUnreader) free() int {
+	u := a.u
+	low := u.cursor
+	if pin, ok := u.pin(); ok && pin < low {
+		low = pin
+	}
+	return int(u.cb.Size() - (u.bytesRead - low))
+}
+
+// notify signals Available without blocking.
+func (a *AsyncUnreader) notify() {
+	select {
+	case a.avail <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until n bytes are buffered after the cursor, the producer
+// has exited, or pinned bytes leave no room for more.
+func (a *AsyncUnreader) wait(n int) {
+	n = min(n, int(a.u.cb.Size()))
+	for int(a.u.Buffered()) < n && a.running && a.free() > 0 {
+		a.need = n
+		a.cond.Broadcast()
+		a.cond.Wait()
+	}
+	a.need = 0
+}
+
+// Available returns a channel that receives after new bytes are buffered
+// and when the producer ends, such as at the end of the stream. Receiving
+// doesn't mean any bytes are left to read; use Buffered or PeekAvailable.
+func (a *AsyncUnreader) Available() <-chan struct{} {
+	return a.avail
+}
+
+// Do calls f with the Unreader while holding the lock, as SyncUnreader.Do
+// does. Reads in f that need more than is buffered fail with ErrWouldBlock
+// while the producer is running.
+func (a *AsyncUnreader) Do(f func(u *Unreader)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f(a.u)
+	a.cond.Broadcast()
+}
+
+// Read is like Unreader.Read, waiting for the producer if nothing is
+// buffered.
+func (a *AsyncUnreader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.wait(1)
+	n, err := a.u.Read(p)
+	a.cond.Broadcast()
+	return n, err
+}
+
+// Peek is like Unreader.Peek, waiting for the producer until n bytes are
+// buffered. It returns a copy of the bytes.
+func (a *AsyncUnreader) Peek(n int) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.wait(n)
+	b, err := a.u.Peek(n)
+	return append([]byte(nil), b...), err
+}
+
+// PeekAvailable returns a copy of the bytes buffered after the cursor,
+// without waiting.
+func (a *AsyncUnreader) PeekAvailable() []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]byte(nil), a.u.PeekAvailable()...)
+}
+
+// Unread is like Unreader.Unread.
+func (a *AsyncUnreader) Unread(c int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.u.Unread(c)
+}
+
+// Discard is like Unreader.Discard, waiting for the producer as needed.
+func (a *AsyncUnreader) Discard(n int64) (discarded int64, err error) {
+	if n < 0 {
+		return 0, ErrNegativeCount
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for discarded < n {
+		a.wait(1)
+		if a.u.Buffered() == 0 {
+			_, err = a.u.Peek(1)
+			return discarded, err
+		}
+		k, _ := a.u.Discard(min(n-discarded, a.u.Buffered()))
+		discarded += k
+		a.cond.Broadcast()
+	}
+	return discarded, nil
+}
+
+// Buffered is like Unreader.Buffered.
+func (a *AsyncUnreader) Buffered() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.u.Buffered()
+}
+
+// Cursor is like Unreader.Cursor.
+func (a *AsyncUnreader) Cursor() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.u.Cursor()
+}
+
+// Stop stops the producer, waiting for a read in flight to return, and
+// hands the Unreader back for direct use. Bytes the producer read that
+// didn't fit in the buffer, and any error it got, are returned by the
+// Unreader's next reads of the underlying reader.
+func (a *AsyncUnreader) Stop() *Unreader {
+	a.mu.Lock()
+	a.quit = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+	<-a.done
+	a.u.rd = &watchdogReader{r: a.src, buf: a.pending, err: a.err}
+	return a.u
+}
+
+// Close stops the producer and closes the Unreader, which closes the
+// underlying reader and ends a read in flight for most readers.
+func (a *AsyncUnreader) Close() error {
+	a.mu.Lock()
+	a.quit = true
+	a.u.rd = a.src
+	err := a.u.Close()
+	a.cond.Broadcast()
+	a.mu.Unlock()
+	<-a.done
+	return err
+}
@@ -0,0 +1,123 @@
+package unreader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// chanReader returns the slices sent on it, one per Read, and io.EOF once
+// it's closed. A send completes only when the reader is read.
+type chanReader chan []byte
+
+func (c chanReader) Read(p []byte) (int, error) {
+	b, ok := <-c
+	if !ok {
+		return 0, io.EOF
+	}
+	return copy(p, b), nil
+}
+
+// send delivers b to the next Read of c, failing the test if no Read comes.
+func (c chanReader) send(t *testing.T, b string) {
+	t.Helper()
+	select {
+	case c <- []byte(b):
+	case <-time.After(time.Second):
+		t.Fatalf("no read for %q", b)
+	}
+}
+
+// idle fails the test if c is read soon.
+func (c chanReader) idle(t *testing.T, why string) {
+	t.Helper()
+	select {
+	case c <- []byte("!"):
+		t.Fatalf("read %s", why)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestAsyncUnreader(t *testing.T) {
+	src := make(chanReader)
+	u, _ := NewUnreader(16, src)
+	a := NewAsyncUnreader(u, 4, 12)
+
+	src.send(t, "0123456789ab")
+	for a.Buffered() < 12 {
+		<-a.Available()
+	}
+	// the producer pauses at the high-water mark
+	src.idle(t, "past the high-water mark")
+	if b := a.PeekAvailable(); string(b) != "0123456789ab" {
+		t.Fatalf("PeekAvailable = %q", b)
+	}
+
+	// and resumes only once the buffered bytes drop to the low-water mark
+	a.Discard(6)
+	src.idle(t, "above the low-water mark")
+	a.Discard(2)
+	src.send(t, "cdefghij")
+	p, err := a.Peek(8)
+	if err != nil || string(p) != "89abcdef" {
+		t.Fatalf("Peek = %q, %v", p, err)
+	}
+	a.Unread(3)
+
+	close(src)
+	got, err := io.ReadAll(a)
+	if err != nil || string(got) != "56789abcdefghij" {
+		t.Fatalf("ReadAll = %q, %v", got, err)
+	}
+	select {
+	case <-a.Available():
+	default:
+		t.Fatal("no notification at end of stream")
+	}
+}
+
+func TestAsyncUnreaderGreedy(t *testing.T) {
+	src := make(chanReader)
+	u, _ := New(src, WithBufferSize(16), WithGreedyRead(true))
+	a := NewAsyncUnreader(u, 0, 4)
+	defer a.Close()
+	defer close(src)
+
+	src.send(t, "abcd")
+	for a.Buffered() < 4 {
+		<-a.Available()
+	}
+	p := make([]byte, 8)
+	if n, err := a.Read(p); n != 4 || err != nil {
+		t.Fatalf("greedy Read = %d, %v, want 4 bytes and no error", n, err)
+	}
+	a.Do(func(u *Unreader) {
+		if _, err := u.Read(p); err != ErrWouldBlock {
+			t.Errorf("next Read = %v, want ErrWouldBlock", err)
+		}
+	})
+}
+
+func TestAsyncUnreaderStop(t *testing.T) {
+	u, _ := NewUnreader(8, bytes.NewReader([]byte("0123456789abcdef")))
+	a := NewAsyncUnreader(u, 0, 0)
+	if b, err := a.Peek(2); err != nil || string(b) != "01" {
+		t.Fatalf("Peek = %q, %v", b, err)
+	}
+	u = a.Stop()
+	got, err := io.ReadAll(u)
+	if err != nil || string(got) != "0123456789abcdef" {
+		t.Fatalf("ReadAll after Stop = %q, %v", got, err)
+	}
+
+	pr, _ := io.Pipe()
+	u, _ = NewUnreader(8, pr)
+	a = NewAsyncUnreader(u, 0, 0)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Read(make([]byte, 1)); err != ErrClosed {
+		t.Fatalf("Read after Close = %v", err)
+	}
+}
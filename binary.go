@@ -0,0 +1,229 @@
+package unreader
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ReadUint16 reads a 16-bit unsigned integer in the given byte order, such
+// as binary.BigEndian. If it can't read the whole integer, ReadUint16
+// consumes nothing, so it can be retried once more data arrives. The error
+// is then io.EOF if the stream ended before the integer,
+// io.ErrUnexpectedEOF if it ended partway through, or why the read fell
+// short.
+func (u *Unreader) ReadUint16(order binary.ByteOrder) (uint16, error) {
+	b, err := u.readFixed(2)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint16(b), nil
+}
+
+// ReadUint32 is like ReadUint16 but reads a 32-bit integer.
+func (u *Unreader) ReadUint32(order binary.ByteOrder) (uint32, error) {
+	b, err := u.readFixed(4)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint32(b), nil
+}
+
+// ReadUint64 is like ReadUint16 but reads a 64-bit integer.
+func (u *Unreader) ReadUint64(order binary.ByteOrder) (uint64, error) {
+	b, err := u.readFixed(8)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint64(b), nil
+}
+
+// ReadInt16 is like ReadUint16 but reads a two's complement signed integer.
+func (u *Unreader) ReadInt16(order binary.ByteOrder) (int16, error) {
+	v, err := u.ReadUint16(order)
+	return int16(v), err
+}
+
+// ReadInt32 is like ReadInt16 but reads a 32-bit integer.
+func (u *Unreader) ReadInt32(order binary.ByteOrder) (int32, error) {
+	v, err := u.ReadUint32(order)
+	return int32(v), err
+}
+
+// ReadInt64 is like ReadInt16 but reads a 64-bit integer.
+func (u *Unreader) ReadInt64(order binary.ByteOrder) (int64, error) {
+	v, err := u.ReadUint64(order)
+	return int64(v), err
+}
+
+// readFixed consumes the next n bytes and returns them, or consumes nothing
+// if fewer than n can be read. The bytes are only valid until the next
+// read.
+func (u *Unreader) readFixed(n int) ([]byte, error) {
+	u.clearLast()
+	b, err := u.Peek(n)
+	if len(b) < n {
+		if err == io.EOF && len(b) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	u.cursor += int64(n)
+	u.lastTokenSize = n
+	return b, nil
+}
+
+// ReadFloat32 reads an IEEE 754 binary32 floating-point number in the given
+// byte order, handling short reads as ReadUint16 does. Unlike ReadFloat, it
+// reads the binary encoding, not text.
+func (u *Unreader) ReadFloat32(order binary.ByteOrder) (float32, error) {
+	v, err := u.ReadUint32(order)
+	return math.Float32frombits(v), err
+}
+
+// ReadFloat64 is like ReadFloat32 but reads a binary64 number.
+func (u *Unreader) ReadFloat64(order binary.ByteOrder) (float64, error) {
+	v, err := u.ReadUint64(order)
+	return math.Float64frombits(v), err
+}
+
+// ReadUvarint reads an unsigned integer encoded as by binary.PutUvarint,
+// the varint encoding protocol buffers use. Unlike binary.ReadUvarint, it
+// consumes nothing if the integer is cut short, returning the error as
+// ReadUint16 does so the read can be retried once more data arrives. It
+// returns ErrOverflow, also consuming nothing, if the integer doesn't fit in
+// 64 bits.
+func (u *Unreader) ReadUvarint() (uint64, error) {
+	b, err := u.peekVarint()
+	if err != nil {
+		return 0, err
+	}
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, ErrOverflow
+	}
+	u.cursor += int64(n)
+	u.lastTokenSize = n
+	return v, nil
+}
+
+// ReadVarint is like ReadUvarint but reads a signed integer encoded as by
+// binary.PutVarint, with zigzag encoding.
+func (u *Unreader) ReadVarint() (int64, error) {
+	b, err := u.peekVarint()
+	if err != nil {
+		return 0, err
+	}
+	v, n := binary.Varint(b)
+	if n <= 0 {
+		return 0, ErrOverflow
+	}
+	u.cursor += int64(n)
+	u.lastTokenSize = n
+	return v, nil
+}
+
+// peekVarint returns the bytes of the varint at the cursor, up to and
+// including the first without its continuation bit, without consuming
+// them. It peeks a byte at a time so that it never waits for more bytes
+// than the varint has.
+func (u *Unreader) peekVarint() ([]byte, error) {
+	u.clearLast()
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		b, err := u.Peek(i + 1)
+		if len(b) <= i {
+			if err == io.EOF && i > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if b[i] < 0x80 {
+			return b, nil
+		}
+	}
+	return nil, ErrOverflow
+}
+
+// ReadULEB128 reads an unsigned LEB128 integer, as used by DWARF and
+// WebAssembly. The encoding is the same as the one ReadUvarint reads, and
+// short reads and overflow are handled the same way.
+func (u *Unreader) ReadULEB128() (uint64, error) {
+	return u.ReadUvarint()
+}
+
+// ReadSLEB128 reads a signed LEB128 integer, whose last byte is sign
+// extended, as used by DWARF and WebAssembly. Short reads and overflow are
+// handled as by ReadUvarint.
+func (u *Unreader) ReadSLEB128() (int64, error) {
+	b, err := u.peekVarint()
+	if err != nil {
+		return 0, err
+	}
+	var v int64
+	var shift uint
+	for i, c := range b {
+		if i == binary.MaxVarintLen64-1 && c != 0 && c != 0x7f {
+			return 0, ErrOverflow
+		}
+		v |= int64(c&0x7f) << shift
+		shift += 7
+		if c < 0x80 && shift < 64 && c&0x40 != 0 {
+			v |= -1 << shift
+		}
+	}
+	u.cursor += int64(len(b))
+	u.lastTokenSize = len(b)
+	return v, nil
+}
+
+// ReadZigzag32 reads a zigzag-encoded 32-bit signed integer stored as a
+// varint, as in the Thrift compact protocol. It's like ReadVarint, but
+// returns ErrOverflow for values that don't fit in 32 bits.
+func (u *Unreader) ReadZigzag32() (int32, error) {
+	b, err := u.peekVarint()
+	if err != nil {
+		return 0, err
+	}
+	v, n := binary.Uvarint(b)
+	if n <= 0 || v > math.MaxUint32 {
+		return 0, ErrOverflow
+	}
+	u.cursor += int64(n)
+	u.lastTokenSize = n
+	return int32(uint32(v)>>1) ^ -int32(v&1), nil
+}
+
+// PeekBinary decodes the upcoming bytes into data, as binary.Read does,
+// without consuming them, so that a header can be examined before deciding
+// whether to read it. data must be a pointer to a fixed-size value or a
+// slice of fixed-size values. If not enough bytes can be peeked, it returns
+// an *IncompleteError, or io.EOF if the stream has ended.
+func (u *Unreader) PeekBinary(order binary.ByteOrder, data any) error {
+	size := binary.Size(data)
+	if size < 0 {
+		return fmt.Errorf("unreader: PeekBinary: invalid type %T", data)
+	}
+	b, err := u.Peek(size)
+	if len(b) < size {
+		return incomplete(size, b, err)
+	}
+	_, err = binary.Decode(b, order, data)
+	return err
+}
+
+// AlignTo discards padding bytes until the cursor is a multiple of n,
+// returning how many were skipped. Alignments of 1 or less need no padding.
+// If the stream ends within the padding, AlignTo returns the error as
+// Discard does.
+func (u *Unreader) AlignTo(n int) (int, error) {
+	if n < 0 {
+		return 0, ErrNegativeCount
+	}
+	if n <= 1 {
+		return 0, nil
+	}
+	pad := (int64(n) - u.cursor%int64(n)) % int64(n)
+	d, err := u.Discard(pad)
+	return int(d), err
+}
@@ -0,0 +1,281 @@
+package unreader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// stallReader returns parts in turn, with an error in place of each empty
+// part, as a network read might time out between packets.
+type stallReader struct {
+	parts []string
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	if len(s.parts) == 0 {
+		return 0, io.EOF
+	}
+	part := s.parts[0]
+	if part == "" {
+		s.parts = s.parts[1:]
+		return 0, errors.New("stall")
+	}
+	n := copy(p, part)
+	if s.parts[0] = part[n:]; s.parts[0] == "" {
+		s.parts = s.parts[1:]
+	}
+	return n, nil
+}
+
+func TestReadFixed(t *testing.T) {
+	tests := []struct {
+		in   string
+		read func(u *Unreader) (int64, error)
+		want int64
+		err  error
+	}{
+		{"\x01\x02", func(u *Unreader) (int64, error) {
+			v, err := u.ReadUint16(binary.BigEndian)
+			return int64(v), err
+		}, 0x0102, nil},
+		{"\x01\x02\x03\x04", func(u *Unreader) (int64, error) {
+			v, err := u.ReadUint32(binary.LittleEndian)
+			return int64(v), err
+		}, 0x04030201, nil},
+		{"\x01\x02\x03\x04\x05\x06\x07\x08", func(u *Unreader) (int64, error) {
+			v, err := u.ReadUint64(binary.BigEndian)
+			return int64(v), err
+		}, 0x0102030405060708, nil},
+		{"\xff\xfe", func(u *Unreader) (int64, error) {
+			v, err := u.ReadInt16(binary.LittleEndian)
+			return int64(v), err
+		}, -257, nil},
+		{"\x01\x02\x03", func(u *Unreader) (int64, error) {
+			v, err := u.ReadUint32(binary.BigEndian)
+			return int64(v), err
+		}, 0, io.ErrUnexpectedEOF},
+		{"", func(u *Unreader) (int64, error) {
+			v, err := u.ReadUint64(binary.LittleEndian)
+			return int64(v), err
+		}, 0, io.EOF},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		v, err := tt.read(u)
+		if v != tt.want || err != tt.err {
+			t.Errorf("%q: read %#x, %v, want %#x, %v", tt.in, v, err, tt.want, tt.err)
+		}
+		if err != nil && u.Cursor() != 0 {
+			t.Errorf("%q: cursor moved to %d on error", tt.in, u.Cursor())
+		}
+	}
+}
+
+func TestReadFixedStall(t *testing.T) {
+	u, _ := NewUnreader(16, &stallReader{parts: []string{"\x01\x02\x03", "", "\x04\x05\x06"}})
+	if v, err := u.ReadUint16(binary.BigEndian); v != 0x0102 || err != nil {
+		t.Fatalf("ReadUint16() = %#x, %v", v, err)
+	}
+	if _, err := u.ReadUint32(binary.LittleEndian); err == nil || u.Cursor() != 2 {
+		t.Fatalf("ReadUint32() error = %v at cursor %d, want stall at 2", err, u.Cursor())
+	}
+	if v, err := u.ReadUint32(binary.LittleEndian); v != 0x06050403 || err != nil {
+		t.Fatalf("ReadUint32() after stall = %#x, %v", v, err)
+	}
+}
+
+func TestReadVarint(t *testing.T) {
+	tests := []struct {
+		in     []byte
+		signed bool
+		want   int64
+		err    error
+	}{
+		{binary.AppendUvarint(nil, 300), false, 300, nil},
+		{binary.AppendVarint(nil, -5), true, -5, nil},
+		{binary.AppendVarint(nil, math.MinInt64), true, math.MinInt64, nil},
+		{[]byte{0x80, 0x80}, false, 0, io.ErrUnexpectedEOF},
+		{nil, true, 0, io.EOF},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, false, 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, bytes.NewReader(tt.in))
+		var v int64
+		var err error
+		if tt.signed {
+			v, err = u.ReadVarint()
+		} else {
+			var uv uint64
+			uv, err = u.ReadUvarint()
+			v = int64(uv)
+		}
+		if v != tt.want || err != tt.err {
+			t.Errorf("% x: read %d, %v, want %d, %v", tt.in, v, err, tt.want, tt.err)
+		}
+		if err != nil && u.Cursor() != 0 {
+			t.Errorf("% x: cursor moved to %d on error", tt.in, u.Cursor())
+		}
+	}
+}
+
+func TestReadVarintStall(t *testing.T) {
+	var buf []byte
+	buf = binary.AppendUvarint(buf, 300)
+	buf = binary.AppendUvarint(buf, 1<<40)
+	u, _ := NewUnreader(16, &stallReader{parts: []string{string(buf[:1]), "", string(buf[1:4]), "", string(buf[4:])}})
+	if _, err := u.ReadUvarint(); err == nil || u.Cursor() != 0 {
+		t.Fatalf("ReadUvarint() error = %v at cursor %d, want stall at 0", err, u.Cursor())
+	}
+	if v, err := u.ReadUvarint(); v != 300 || err != nil {
+		t.Fatalf("ReadUvarint() = %d, %v, want 300", v, err)
+	}
+	if _, err := u.ReadUvarint(); err == nil || u.Cursor() != 2 {
+		t.Fatalf("ReadUvarint() error = %v at cursor %d, want stall at 2", err, u.Cursor())
+	}
+	if v, err := u.ReadUvarint(); v != 1<<40 || err != nil {
+		t.Fatalf("ReadUvarint() = %d, %v, want 1<<40", v, err)
+	}
+}
+
+func TestReadLEB128(t *testing.T) {
+	// examples from the DWARF specification
+	for _, tt := range []struct {
+		in   []byte
+		want int64
+	}{
+		{[]byte{0x02}, 2},
+		{[]byte{0x7e}, -2},
+		{[]byte{0xff, 0x00}, 127},
+		{[]byte{0x81, 0x7f}, -127},
+		{[]byte{0x80, 0x01}, 128},
+		{[]byte{0x80, 0x7f}, -128},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f}, math.MinInt64},
+	} {
+		if v, err := NewUnreaderBytes(tt.in).ReadSLEB128(); v != tt.want || err != nil {
+			t.Errorf("ReadSLEB128(% x) = %d, %v, want %d", tt.in, v, err, tt.want)
+		}
+	}
+	if v, err := NewUnreaderBytes([]byte{0xe5, 0x8e, 0x26}).ReadULEB128(); v != 624485 || err != nil {
+		t.Errorf("ReadULEB128() = %d, %v, want 624485", v, err)
+	}
+	over := []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}
+	if _, err := NewUnreaderBytes(over).ReadSLEB128(); err != ErrOverflow {
+		t.Errorf("ReadSLEB128(% x) error = %v, want ErrOverflow", over, err)
+	}
+}
+
+func TestReadZigzag32(t *testing.T) {
+	for _, tt := range []struct {
+		in   []byte
+		want int32
+		err  error
+	}{
+		{[]byte{0x03}, -2, nil},
+		{[]byte{0x04}, 2, nil},
+		{binary.AppendUvarint(nil, math.MaxUint32), math.MinInt32, nil},
+		{binary.AppendUvarint(nil, 1<<33), 0, ErrOverflow},
+	} {
+		if v, err := NewUnreaderBytes(tt.in).ReadZigzag32(); v != tt.want || err != tt.err {
+			t.Errorf("ReadZigzag32(% x) = %d, %v, want %d, %v", tt.in, v, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestPeekBinary(t *testing.T) {
+	var h struct {
+		Magic uint16
+		Len   uint32
+	}
+	u, _ := NewUnreader(16, strings.NewReader("\xca\xfe\x00\x00\x00\x02xy"))
+	if err := u.PeekBinary(binary.BigEndian, &h); err != nil || h.Magic != 0xcafe || h.Len != 2 {
+		t.Fatalf("PeekBinary() = %+v, %v", h, err)
+	}
+	if u.Cursor() != 0 {
+		t.Fatalf("PeekBinary() moved the cursor to %d", u.Cursor())
+	}
+	words := make([]uint16, 4)
+	if err := u.PeekBinary(binary.LittleEndian, words); err != nil || words[0] != 0xfeca || words[3] != 0x7978 {
+		t.Fatalf("PeekBinary() = %#x, %v", words, err)
+	}
+	var big [9]uint8
+	var ie *IncompleteError
+	if err := u.PeekBinary(binary.BigEndian, &big); !errors.As(err, &ie) || ie.Need != 1 || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("PeekBinary() of 9 bytes error = %v, want 1 more byte needed", err)
+	}
+	if err := u.PeekBinary(binary.BigEndian, &struct{ S []int }{}); err == nil {
+		t.Fatal("PeekBinary() of a variable-size type succeeded")
+	}
+	u.Discard(8)
+	if err := u.PeekBinary(binary.BigEndian, &h); err != io.EOF {
+		t.Fatalf("PeekBinary() at end error = %v, want io.EOF", err)
+	}
+}
+
+func TestAlignTo(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor int64
+		n      int
+		skip   int
+		err    error
+	}{
+		{"aligned", 8, 4, 0, nil},
+		{"pad", 5, 4, 3, nil},
+		{"pad 8", 1, 8, 7, nil},
+		{"one", 3, 1, 0, nil},
+		{"zero", 3, 0, 0, nil},
+		{"padding past EOF", 9, 8, 1, io.EOF},
+		{"negative", 3, -2, 0, ErrNegativeCount},
+	}
+	for _, tt := range tests {
+		u := NewUnreaderString("0123456789")
+		u.Discard(tt.cursor)
+		if n, err := u.AlignTo(tt.n); n != tt.skip || err != tt.err {
+			t.Errorf("%s: AlignTo(%d) at %d = %d, %v, want %d, %v", tt.name, tt.n, tt.cursor, n, err, tt.skip, tt.err)
+		}
+		if u.Cursor() != tt.cursor+int64(tt.skip) {
+			t.Errorf("%s: cursor at %d after AlignTo", tt.name, u.Cursor())
+		}
+	}
+}
+
+func TestReadFloat32(t *testing.T) {
+	f32 := func(order binary.ByteOrder) func(u *Unreader) (float64, error) {
+		return func(u *Unreader) (float64, error) {
+			v, err := u.ReadFloat32(order)
+			return float64(v), err
+		}
+	}
+	f64 := func(order binary.ByteOrder) func(u *Unreader) (float64, error) {
+		return func(u *Unreader) (float64, error) { return u.ReadFloat64(order) }
+	}
+	tests := []struct {
+		in   string
+		read func(u *Unreader) (float64, error)
+		want float64
+		err  error
+	}{
+		{"\x3f\xc0\x00\x00", f32(binary.BigEndian), 1.5, nil},
+		{"\x00\x00\xc0\xbf", f32(binary.LittleEndian), -1.5, nil},
+		{"\x7f\x80\x00\x00", f32(binary.BigEndian), math.Inf(1), nil},
+		{"\x40\x09\x21\xfb\x54\x44\x2d\x18", f64(binary.BigEndian), math.Pi, nil},
+		{"\x18\x2d\x44\x54\xfb\x21\x09\x40", f64(binary.LittleEndian), math.Pi, nil},
+		{"\x3f\xc0\x00", f32(binary.BigEndian), 0, io.ErrUnexpectedEOF},
+		{"\x40\x09\x21\xfb", f64(binary.BigEndian), 0, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		v, err := tt.read(u)
+		if v != tt.want || err != tt.err {
+			t.Errorf("%q: read %v, %v, want %v, %v", tt.in, v, err, tt.want, tt.err)
+		}
+		if err != nil && u.Cursor() != 0 {
+			t.Errorf("%q: cursor moved to %d on error", tt.in, u.Cursor())
+		}
+	}
+}
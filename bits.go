@@ -0,0 +1,114 @@
+package unreader
+
+// BitOrder is the order in which a BitReader takes the bits of each byte.
+type BitOrder int
+
+const (
+	MSBFirst BitOrder = iota // most significant bit first, as in network headers
+	LSBFirst                 // least significant bit first, as in DEFLATE
+)
+
+// BitReader reads an Unreader's stream a bit at a time. Its position is
+// the Unreader's cursor plus a count of bits already taken from the byte
+// at the cursor, which is consumed only once all its bits have been. Moving
+// the cursor with the Unreader's own methods, such as Unread or Read,
+// leaves the BitReader at the start of the byte at the new cursor.
+type BitReader struct {
+	u     *Unreader
+	order BitOrder
+	bit   int   // bits taken from the byte at at
+	at    int64 // cursor when bit was set
+}
+
+// NewBitReader returns a BitReader reading from u in the given bit order.
+func NewBitReader(u *Unreader, order BitOrder) *BitReader {
+	return &BitReader{u: u, order: order}
+}
+
+// sync drops the bit position if the cursor was moved under it.
+func (br *BitReader) sync() {
+	if br.u.cursor != br.at {
+		br.bit, br.at = 0, br.u.cursor
+	}
+}
+
+// Offset returns the absolute position of the next bit in the stream.
+func (br *BitReader) Offset() int64 {
+	br.sync()
+	return br.u.cursor*8 + int64(br.bit)
+}
+
+// ReadBits reads n bits, at most 64, and returns them as an integer with
+// the first bit read as the most significant bit for MSBFirst, or as the
+// least significant for LSBFirst. If not all n bits can be read, nothing is
+// consumed and the error is as for ReadFrame.
+func (br *BitReader) ReadBits(n int) (uint64, error) {
+	if n < 0 {
+		return 0, ErrNegativeCount
+	}
+	if n > 64 {
+		return 0, ErrOverflow
+	}
+	br.sync()
+	need := (br.bit + n + 7) / 8
+	p, err := br.u.Peek(need)
+	if len(p) < need {
+		return 0, incomplete(need, p, err)
+	}
+	var v uint64
+	for i := 0; i < n; i++ {
+		off := br.bit + i
+		if br.order == LSBFirst {
+			v |= uint64(p[off/8]>>(off%8)&1) << i
+		} else {
+			v = v<<1 | uint64(p[off/8]>>(7-off%8)&1)
+		}
+	}
+	br.u.clearLast()
+	br.seek(br.Offset() + int64(n))
+	return v, nil
+}
+
+// ReadBit reads a single bit.
+func (br *BitReader) ReadBit() (bool, error) {
+	v, err := br.ReadBits(1)
+	return v == 1, err
+}
+
+// UnreadBits moves back n bits. It fails as Unread does if that would go
+// further back than the Unreader's buffer holds, or than the start of the
+// stream.
+func (br *BitReader) UnreadBits(n int) error {
+	if n < 0 {
+		return ErrNegativeCount
+	}
+	pos := br.Offset() - int64(n)
+	if pos < 0 {
+		// as many bytes as reaching pos would take
+		return br.u.unreadError(br.u.cursor-(pos-7)/8, ErrUnreadBeyondWritten)
+	}
+	if err := br.u.Unread(br.u.cursor - pos/8); err != nil {
+		return err
+	}
+	br.bit, br.at = int(pos%8), br.u.cursor
+	return nil
+}
+
+// Align skips the rest of a partly read byte, returning how many bits were
+// skipped.
+func (br *BitReader) Align() int {
+	br.sync()
+	if br.bit == 0 {
+		return 0
+	}
+	n := 8 - br.bit
+	br.seek(br.Offset() + int64(n))
+	return n
+}
+
+// seek moves to pos, a bit offset at or after the cursor whose bytes are
+// all buffered.
+func (br *BitReader) seek(pos int64) {
+	br.u.cursor = pos / 8
+	br.bit, br.at = int(pos%8), br.u.cursor
+}
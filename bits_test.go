@@ -0,0 +1,72 @@
+package unreader
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadBits(t *testing.T) {
+	in := []byte{0b1011_0010, 0b0111_1100, 0xff}
+	tests := []struct {
+		order BitOrder
+		sizes []int
+		want  []uint64
+		err   error
+	}{
+		{MSBFirst, []int{1, 3, 4, 8}, []uint64{1, 0b011, 0b0010, 0b0111_1100}, nil},
+		{LSBFirst, []int{1, 3, 4, 8}, []uint64{0, 0b001, 0b1011, 0b0111_1100}, nil},
+		{MSBFirst, []int{12, 12}, []uint64{0xb27, 0xcff}, nil},
+		{LSBFirst, []int{12, 12}, []uint64{0xcb2, 0xff7}, nil},
+		{MSBFirst, []int{20, 8}, []uint64{0xb27c_f}, io.ErrUnexpectedEOF},
+		{MSBFirst, []int{65}, nil, ErrOverflow},
+		{MSBFirst, []int{-1}, nil, ErrNegativeCount},
+	}
+	for _, tt := range tests {
+		br := NewBitReader(NewUnreaderBytes(in), tt.order)
+		var got []uint64
+		var err error
+		for _, n := range tt.sizes {
+			var v uint64
+			if v, err = br.ReadBits(n); err != nil {
+				break
+			}
+			got = append(got, v)
+		}
+		if !errors.Is(err, tt.err) || len(got) != len(tt.want) {
+			t.Errorf("%v ReadBits%v = %x, %v; want %x, %v", tt.order, tt.sizes, got, err, tt.want, tt.err)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%v ReadBits%v = %x, want %x", tt.order, tt.sizes, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestUnreadBits(t *testing.T) {
+	br := NewBitReader(NewUnreaderBytes([]byte{0xa5, 0x0f}), MSBFirst)
+	br.ReadBits(11)
+	if err := br.UnreadBits(6); err != nil || br.Offset() != 5 {
+		t.Fatalf("UnreadBits(6) = %v, offset %d", err, br.Offset())
+	}
+	if v, _ := br.ReadBits(3); v != 0b101 {
+		t.Fatalf("ReadBits(3) after UnreadBits = %b", v)
+	}
+	var ue *UnreadError
+	if err := br.UnreadBits(9); !errors.As(err, &ue) || ue.Err != ErrUnreadBeyondWritten {
+		t.Fatalf("UnreadBits past the start = %v", err)
+	}
+	if br.Offset() != 8 {
+		t.Fatalf("failed UnreadBits moved to offset %d", br.Offset())
+	}
+	if err := br.UnreadBits(8); err != nil || br.Offset() != 0 {
+		t.Fatalf("UnreadBits to the start = %v, offset %d", err, br.Offset())
+	}
+	br.ReadBits(3)
+	if n := br.Align(); n != 5 || br.Offset() != 8 {
+		t.Fatalf("Align = %d, offset %d", n, br.Offset())
+	}
+}
@@ -0,0 +1,112 @@
+package unreader
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// RewindableBody replaces r.Body with one read through an Unreader with a
+// buffer of size bytes, and returns the Unreader so the body can be peeked
+// or sniffed. r.GetBody is set to rewind the body to its start, which works
+// for as long as the start is still buffered. Closing the new Body doesn't
+// close the original while the body can still be rewound; close the
+// returned Unreader to release it. A request without a body is left as it
+// is, and gets an empty Unreader.
+func RewindableBody(r *http.Request, size int64) (*Unreader, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return NewUnreaderBytes(nil), nil
+	}
+	u, err := NewUnreader(size, r.Body)
+	if err != nil {
+		return nil, err
+	}
+	b := &rewindBody{u: u}
+	r.Body = b
+	r.GetBody = b.rewind
+	return u, nil
+}
+
+// InspectBody returns middleware that makes request bodies rewindable with
+// RewindableBody and calls inspect with the body's Unreader before the next
+// handler. While inspect runs, the start of the body is pinned, so reads
+// past size bytes fail with ErrPinned. The body is then rewound, so the next
+// handler reads all of it. If inspect returns false, the request isn't
+// passed on and inspect is expected to have written a response.
+func InspectBody(size int64, inspect func(w http.ResponseWriter, r *http.Request, u *Unreader) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, err := RewindableBody(r, size)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			u.PushMark("")
+			ok := inspect(w, r, u)
+			u.PopMark()
+			if !ok {
+				return
+			}
+			if err := u.SeekTo(0); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rewindBody is a request body read through an Unreader that can be
+// rewound to its start by GetBody.
+type rewindBody struct {
+	u *Unreader
+
+	mu     sync.Mutex
+	closed bool // closed since the last rewind
+	final  bool // won't be rewound again
+}
+
+func (b *rewindBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return 0, http.ErrBodyReadAfterClose
+	}
+	return b.u.Read(p)
+}
+
+// Close stops reads until the body is rewound, and closes the original body
+// once it can't be rewound again: its start has been evicted, or release
+// was called.
+func (b *rewindBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	if b.final || b.u.bytesRead > b.u.retained() {
+		return b.u.Close()
+	}
+	return nil
+}
+
+// rewind moves the body back to its start and returns it, for GetBody.
+func (b *rewindBody) rewind() (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.u.SeekTo(0); err != nil {
+		return nil, err
+	}
+	b.closed = false
+	return b, nil
+}
+
+// release marks the body as never to be rewound again, closing the
+// original body now if it has already been closed.
+func (b *rewindBody) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.final = true
+	if b.closed {
+		b.u.Close()
+	}
+}
@@ -0,0 +1,74 @@
+package unreader
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInspectBody(t *testing.T) {
+	var sniffed, got string
+	h := InspectBody(8, func(w http.ResponseWriter, r *http.Request, u *Unreader) bool {
+		b, _ := u.Peek(4)
+		sniffed = string(b)
+		if sniffed == "evil" {
+			http.Error(w, "rejected", http.StatusForbidden)
+			return false
+		}
+		u.Discard(6)
+		return true
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+
+	tests := []struct {
+		body string
+		code int
+		got  string
+	}{
+		{"hello, world", http.StatusOK, "hello, world"},
+		{"evil payload", http.StatusForbidden, ""},
+		{"", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		sniffed, got = "", ""
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(tt.body)))
+		if rec.Code != tt.code || got != tt.got {
+			t.Errorf("body %q: code %d, handler read %q; want %d, %q", tt.body, rec.Code, got, tt.code, tt.got)
+		}
+		if want := tt.body[:min(4, len(tt.body))]; sniffed != want {
+			t.Errorf("body %q: sniffed %q, want %q", tt.body, sniffed, want)
+		}
+	}
+}
+
+func TestRewindableBodyGetBody(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader("0123456789"))
+	if _, err := RewindableBody(r, 16); err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(r.Body)
+	r.Body.Close()
+	if _, err := r.Body.Read(make([]byte, 1)); err != http.ErrBodyReadAfterClose {
+		t.Fatalf("Read after Close = %v", err)
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(body); string(b) != "0123456789" {
+		t.Fatalf("GetBody body = %q", b)
+	}
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader("0123456789"))
+	RewindableBody(r, 4)
+	io.ReadAll(r.Body)
+	if _, err := r.GetBody(); !errors.Is(err, ErrUnreadBeyondBuffer) {
+		t.Fatalf("GetBody after eviction = %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package unreader
+
+import (
+	"bytes"
+	"io"
+)
+
+// Encoding is a Unicode encoding scheme, as identified by a byte order mark.
+type Encoding int
+
+const (
+	EncodingUnknown Encoding = iota
+	UTF8
+	UTF16BE
+	UTF16LE
+	UTF32BE
+	UTF32LE
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case UTF8:
+		return "UTF-8"
+	case UTF16BE:
+		return "UTF-16BE"
+	case UTF16LE:
+		return "UTF-16LE"
+	case UTF32BE:
+		return "UTF-32BE"
+	case UTF32LE:
+		return "UTF-32LE"
+	}
+	return "unknown"
+}
+
+// boms lists byte order marks, with UTF-32LE ahead of the UTF-16LE mark it
+// starts with.
+var boms = []struct {
+	mark []byte
+	enc  Encoding
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, UTF8},
+	{[]byte{0x00, 0x00, 0xFE, 0xFF}, UTF32BE},
+	{[]byte{0xFF, 0xFE, 0x00, 0x00}, UTF32LE},
+	{[]byte{0xFE, 0xFF}, UTF16BE},
+	{[]byte{0xFF, 0xFE}, UTF16LE},
+}
+
+// sniffBOM returns the encoding indicated by a byte order mark at the start
+// of b and the length of the mark. more reports whether b is too short to
+// tell yet; at EOF, where no more bytes can follow, it is always false.
+func sniffBOM(b []byte, atEOF bool) (enc Encoding, size int, more bool) {
+	for _, m := range boms {
+		if len(b) < len(m.mark) && bytes.HasPrefix(m.mark, b) {
+			if atEOF {
+				continue
+			}
+			return EncodingUnknown, 0, true
+		}
+		if bytes.HasPrefix(b, m.mark) {
+			return m.enc, len(m.mark), false
+		}
+	}
+	return EncodingUnknown, 0, false
+}
+
+// bomReader strips a byte order mark from the start of r.
+type bomReader struct {
+	r    io.Reader
+	enc  Encoding
+	done bool
+	head []byte // bytes read while detecting that follow the mark
+	err  error
+}
+
+// detect reads just enough of r to tell whether it starts with a byte order
+// mark.
+func (b *bomReader) detect() {
+	b.done = true
+	buf := make([]byte, 0, 4)
+	for empty := 0; ; {
+		enc, size, more := sniffBOM(buf, b.err != nil)
+		if !more {
+			b.enc = enc
+			b.head = buf[size:]
+			return
+		}
+		var n int
+		n, b.err = b.r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if n > 0 || b.err != nil {
+			empty = 0
+		} else if empty++; empty >= maxConsecutiveEmptyReads {
+			b.err = io.ErrNoProgress
+		}
+	}
+}
+
+func (b *bomReader) Read(p []byte) (int, error) {
+	if !b.done {
+		b.detect()
+	}
+	if len(b.head) > 0 {
+		n := copy(p, b.head)
+		b.head = b.head[n:]
+		return n, nil
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.r.Read(p)
+}
+
+// BOM returns the encoding indicated by the byte order mark stripped from
+// the start of the stream, or EncodingUnknown if there was none or stripping
+// isn't enabled with WithBOMStripping. If nothing has been read yet, BOM
+// reads enough of the underlying reader to find out.
+func (u *Unreader) BOM() Encoding {
+	if u.bom == nil {
+		return EncodingUnknown
+	}
+	if !u.bom.done {
+		u.bom.detect()
+	}
+	return u.bom.enc
+}
@@ -0,0 +1,55 @@
+package unreader
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBOM(t *testing.T) {
+	tests := []struct {
+		in   string
+		enc  Encoding
+		want string
+	}{
+		{"\xEF\xBB\xBFhi", UTF8, "hi"},
+		{"\xFF\xFE\x00\x00x", UTF32LE, "x"},
+		{"\xFF\xFEh\x00", UTF16LE, "h\x00"},
+		{"\xFF\xFE", UTF16LE, ""},
+		{"\xFF\xFE\x00", UTF16LE, "\x00"},
+		{"\xFFa", EncodingUnknown, "\xFFa"},
+		{"\xFE", EncodingUnknown, "\xFE"},
+	}
+	for _, tt := range tests {
+		u, err := New(iotest.OneByteReader(strings.NewReader(tt.in)), WithBOMStripping(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.want || u.BOM() != tt.enc {
+			t.Errorf("%q: read %q with BOM %v, want %q with %v", tt.in, b, u.BOM(), tt.want, tt.enc)
+		}
+	}
+}
+
+// emptyReader returns no bytes and no error.
+type emptyReader struct{}
+
+func (emptyReader) Read(p []byte) (int, error) { return 0, nil }
+
+func TestBOMNoProgress(t *testing.T) {
+	u, err := New(emptyReader{}, WithBOMStripping(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc := u.BOM(); enc != EncodingUnknown {
+		t.Errorf("BOM() = %v, want unknown", enc)
+	}
+	if _, err := u.ReadByte(); err != io.ErrNoProgress {
+		t.Errorf("ReadByte() error = %v, want io.ErrNoProgress", err)
+	}
+}
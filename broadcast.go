@@ -0,0 +1,139 @@
+package unreader
+
+import (
+	"io"
+	"sync"
+)
+
+// Broadcast returns n Unreaders, configured by opts, that each read all of
+// r, which is read only once. Each has its own buffer, cursor and unreads,
+// so they can be read independently from different goroutines, such as by
+// an IDS matcher and the real handler of a connection. Bytes read from r
+// are held until every Unreader has taken them, at most window bytes at a
+// time: one that gets window bytes ahead of the slowest waits for it to
+// catch up. An Unreader that stops reading without being closed therefore
+// stalls the others. r is closed, if it implements io.Closer, once all of
+// the Unreaders are. A window of zero or less means the default buffer
+// size.
+func Broadcast(r io.Reader, n int, window int, opts ...Option) ([]*Unreader, error) {
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+	if window <= 0 {
+		window = defaultBufferSize
+	}
+	b := &broadcast{src: r, window: window, offs: make([]int64, n), open: n}
+	b.cond = sync.NewCond(&b.mu)
+	us := make([]*Unreader, n)
+	for i := range us {
+		u, err := New(&branch{b: b, i: i}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		us[i] = u
+	}
+	return us, nil
+}
+
+// broadcast holds the bytes read from src that not every branch has taken.
+type broadcast struct {
+	src io.Reader
+
+	mu      sync.Mutex
+	cond    *sync.Cond // signaled when bytes arrive or a branch advances
+	buf     []byte     // bytes from base on
+	base    int64      // stream offset of buf[0]
+	window  int        // most bytes buf holds
+	scratch []byte     // read into by the branch reading src
+	offs    []int64    // stream offset of each branch, or -1 once closed
+	open    int        // branches not closed
+	reading bool       // a branch is reading src
+	err     error      // error from src, returned to each branch at the end
+}
+
+// branch is the underlying reader of one of Broadcast's Unreaders.
+type branch struct {
+	b *broadcast
+	i int
+}
+
+func (br *branch) Read(p []byte) (int, error) {
+	b := br.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		off := b.offs[br.i]
+		if off < 0 {
+			return 0, ErrClosed
+		}
+		if end := b.base + int64(len(b.buf)); off < end {
+			n := copy(p, b.buf[off-b.base:])
+			b.offs[br.i] += int64(n)
+			b.trim()
+			return n, nil
+		}
+		if b.err != nil {
+			return 0, b.err
+		}
+		if b.reading || len(b.buf) >= b.window {
+			b.cond.Wait()
+			continue
+		}
+		b.read()
+	}
+}
+
+// read reads src into buf, without holding the lock while it waits.
+func (b *broadcast) read() {
+	if b.scratch == nil {
+		b.scratch = make([]byte, min(b.window, fillSize))
+	}
+	p := b.scratch[:min(b.window-len(b.buf), len(b.scratch))]
+	b.reading = true
+	b.mu.Unlock()
+	n, err := b.src.Read(p)
+	b.mu.Lock()
+	b.reading = false
+	b.buf = append(b.buf, p[:n]...)
+	if err != nil {
+		b.err = err
+	}
+	b.cond.Broadcast()
+}
+
+// trim drops the bytes every open branch has taken.
+func (b *broadcast) trim() {
+	low := b.base + int64(len(b.buf))
+	for _, off := range b.offs {
+		if off >= 0 {
+			low = min(low, off)
+		}
+	}
+	if k := int(low - b.base); k > 0 {
+		b.buf = b.buf[:copy(b.buf, b.buf[k:])]
+		b.base = low
+		b.cond.Broadcast()
+	}
+}
+
+// Close stops the branch from holding back the others, closing src once
+// every branch is closed.
+func (br *branch) Close() error {
+	b := br.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.offs[br.i] < 0 {
+		return nil
+	}
+	b.offs[br.i] = -1
+	b.open--
+	b.trim()
+	b.cond.Broadcast()
+	if b.open > 0 {
+		return nil
+	}
+	if c, ok := b.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package unreader
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBroadcast(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 500)
+	src := &closeReader{Reader: bytes.NewReader(data)}
+	us, err := Broadcast(src, 3, 64, WithBufferSize(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	got := make([][]byte, len(us))
+	for i, u := range us {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := make([]byte, 1+7*i)
+			for {
+				n, err := u.Read(p)
+				got[i] = append(got[i], p[:n]...)
+				if i == 1 && n > 2 {
+					// lookbehind is per Unreader
+					u.Unread(2)
+					got[i] = got[i][:len(got[i])-2]
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for i, b := range got {
+		if !bytes.Equal(b, data) {
+			t.Errorf("Unreader %d read %d bytes, want %d", i, len(b), len(data))
+		}
+	}
+	for i, u := range us[:len(us)-1] {
+		if u.Close(); src.closed {
+			t.Fatalf("source closed with Unreader %d", i)
+		}
+	}
+	us[len(us)-1].Close()
+	if !src.closed {
+		t.Fatal("source not closed with the last Unreader")
+	}
+}
+
+func TestBroadcastFlowControl(t *testing.T) {
+	us, _ := Broadcast(bytes.NewReader(make([]byte, 1000)), 2, 100, WithBufferSize(10))
+	// the fast reader sends its total after every read
+	progress := make(chan int)
+	go func() {
+		defer close(progress)
+		p := make([]byte, 10)
+		total := 0
+		for {
+			k, err := us[0].Read(p)
+			total += k
+			progress <- total
+			if err != nil {
+				return
+			}
+		}
+	}()
+	total := 0
+	for total < 100 {
+		select {
+		case total = <-progress:
+		case <-time.After(time.Second):
+			t.Fatalf("fast reader stuck at %d bytes, want the 100 byte window", total)
+		}
+	}
+	select {
+	case total = <-progress:
+		t.Fatalf("fast reader got %d bytes ahead, want the 100 byte window", total)
+	case <-time.After(20 * time.Millisecond):
+	}
+	us[1].Close()
+	for {
+		select {
+		case n, ok := <-progress:
+			if !ok {
+				if total != 1000 {
+					t.Fatalf("fast reader read %d bytes", total)
+				}
+				return
+			}
+			total = n
+		case <-time.After(time.Second):
+			t.Fatal("fast reader still blocked after the slow one closed")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package unreader
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CertFormat is how a certificate or key is stored, as identified by
+// SniffCertFormat.
+type CertFormat int
+
+const (
+	CertFormatUnknown CertFormat = iota
+	PEM                          // base64 blocks between "-----BEGIN" and "-----END" lines
+	DER                          // raw ASN.1, starting with a SEQUENCE
+	PlainText                    // text that isn't PEM
+)
+
+func (f CertFormat) String() string {
+	switch f {
+	case PEM:
+		return "PEM"
+	case DER:
+		return "DER"
+	case PlainText:
+		return "text"
+	}
+	return "unknown"
+}
+
+// certSniffLen is how many bytes SniffCertFormat looks at.
+const certSniffLen = 512
+
+// SniffCertFormat tells whether the stream after the cursor holds PEM, DER
+// or other text, looking at no more than its first 512 bytes, so a loader
+// can decide how to decode it without reading it all. PEM may be preceded
+// by explanatory text, as in OpenSSL output. Nothing is consumed.
+func (u *Unreader) SniffCertFormat() (CertFormat, error) {
+	b, err := u.Sniff(certSniffLen)
+	if err != nil {
+		return CertFormatUnknown, err
+	}
+	begin := []byte("-----BEGIN ")
+	trimmed := bytes.TrimLeftFunc(b, unicode.IsSpace)
+	switch {
+	case bytes.HasPrefix(trimmed, begin) || bytes.Contains(b, append([]byte("\n"), begin...)):
+		return PEM, nil
+	case isDERSequence(b):
+		return DER, nil
+	case len(b) > 0 && isText(b):
+		return PlainText, nil
+	}
+	return CertFormatUnknown, nil
+}
+
+// isDERSequence reports whether b starts with an ASN.1 SEQUENCE, as every
+// certificate and key does. A minimally encoded long form length is enough;
+// small keys, such as Ed25519 ones, have a short form length, and are only
+// taken for DER if the SEQUENCE starts with a tag a key or certificate can,
+// so that text starting with "0" isn't.
+func isDERSequence(b []byte) bool {
+	if len(b) < 3 || b[0] != 0x30 {
+		return false
+	}
+	if b[1] < 0x80 {
+		switch b[2] {
+		case 0x30, 0x02, 0x06, 0xa0: // SEQUENCE, INTEGER, OID, [0]
+			return b[1] >= 2
+		}
+		return false
+	}
+	n := int(b[1] & 0x7f)
+	if n < 1 || n > 4 || len(b) < 2+n || b[2] == 0 {
+		return false
+	}
+	return n > 1 || b[2] >= 0x80
+}
+
+// isText reports whether b is UTF-8 without control characters other than
+// whitespace. An incomplete rune at the end, where b may have been cut
+// short, is allowed.
+func isText(b []byte) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			return !utf8.FullRune(b)
+		}
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return false
+		}
+		b = b[size:]
+	}
+	return true
+}
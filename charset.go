@@ -0,0 +1,91 @@
+package unreader
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// CharsetDetector guesses the character encoding of sample, the first
+// bytes of a stream, returning nil if it can't tell.
+type CharsetDetector func(sample []byte) encoding.Encoding
+
+// DetectCharset is the CharsetDetector Transcode uses by default. It
+// recognizes UTF-8, and UTF-16 with a byte order mark, by their encoding
+// rules and Shift-JIS by its double-byte sequences, falling back to
+// Windows-1252, the superset of Latin-1 that browsers decode Latin-1 as.
+func DetectCharset(sample []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(sample, []byte("\xfe\xff")), bytes.HasPrefix(sample, []byte("\xff\xfe")):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case validUTF8(sample):
+		return unicode.UTF8
+	case isShiftJIS(sample):
+		return japanese.ShiftJIS
+	}
+	return charmap.Windows1252
+}
+
+// validUTF8 is like utf8.Valid but allows an incomplete rune at the end,
+// where sample may have been cut short.
+func validUTF8(sample []byte) bool {
+	for i := max(0, len(sample)-utf8.UTFMax+1); i < len(sample); i++ {
+		if utf8.RuneStart(sample[i]) && !utf8.FullRune(sample[i:]) {
+			sample = sample[:i]
+			break
+		}
+	}
+	return utf8.Valid(sample)
+}
+
+// isShiftJIS reports whether sample has at least one Shift-JIS double-byte
+// character and nothing that isn't valid Shift-JIS.
+func isShiftJIS(sample []byte) bool {
+	double := false
+	for i := 0; i < len(sample); i++ {
+		c := sample[i]
+		switch {
+		case c < 0x80 || 0xa1 <= c && c <= 0xdf:
+			// ASCII or half-width katakana
+		case 0x81 <= c && c <= 0x9f || 0xe0 <= c && c <= 0xfc:
+			if i+1 == len(sample) {
+				return double
+			}
+			i++
+			if t := sample[i]; t < 0x40 || t == 0x7f || t > 0xfc {
+				return false
+			}
+			double = true
+		default:
+			return false
+		}
+	}
+	return double
+}
+
+// Transcode detects the character encoding of the stream after the cursor
+// from up to its next n bytes, using detect or DetectCharset if detect is
+// nil, and returns a new Unreader, configured by opts, that reads the
+// stream decoded to UTF-8, along with the encoding detected. Its cursor,
+// unreads and offsets all work on the decoded bytes. If the encoding is
+// UTF-8 or can't be detected, the stream is read as it is. u must not be
+// read directly afterwards.
+func (u *Unreader) Transcode(n int, detect CharsetDetector, opts ...Option) (*Unreader, encoding.Encoding, error) {
+	sample, err := u.Sniff(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	if detect == nil {
+		detect = DetectCharset
+	}
+	enc := detect(sample)
+	if enc != nil && enc != unicode.UTF8 {
+		opts = append(opts[:len(opts):len(opts)], WithTransform(enc.NewDecoder()))
+	}
+	nu, err := New(u, opts...)
+	return nu, enc, err
+}
@@ -0,0 +1,63 @@
+package unreader
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestDetectCharset(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample string
+		want   encoding.Encoding
+	}{
+		{"ascii", "hello", unicode.UTF8},
+		{"utf-8", "héllo 日本", unicode.UTF8},
+		{"utf-8 cut short", "h\xc3", unicode.UTF8},
+		{"utf-16 bom", "\xff\xfeh\x00", unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)},
+		{"shift-jis", "\x93\xfa\x96\x7b", japanese.ShiftJIS},
+		{"latin-1", "caf\xe9 noir", charmap.Windows1252},
+	}
+	for _, tt := range tests {
+		if enc := DetectCharset([]byte(tt.sample)); enc != tt.want {
+			t.Errorf("%s: DetectCharset(%q) = %v, want %v", tt.name, tt.sample, enc, tt.want)
+		}
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		detect CharsetDetector
+		want   string
+	}{
+		{"utf-8", "héllo", nil, "héllo"},
+		{"latin-1", "caf\xe9 au lait", nil, "café au lait"},
+		{"shift-jis", "\x93\xfa\x96\x7b", nil, "日本"},
+		{"utf-16", "\xff\xfeh\x00i\x00", nil, "hi"},
+		{"custom detector", "\xe9t\xe9", func([]byte) encoding.Encoding { return charmap.ISO8859_1 }, "été"},
+		{"undetected", "\xe9", func([]byte) encoding.Encoding { return nil }, "\xe9"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(64, strings.NewReader(tt.in))
+		tu, _, err := u.Transcode(64, tt.detect)
+		if err != nil {
+			t.Fatalf("%s: Transcode() = %v", tt.name, err)
+		}
+		b, _ := io.ReadAll(tu)
+		if string(b) != tt.want {
+			t.Errorf("%s: read %q, want %q", tt.name, b, tt.want)
+		}
+		// the cursor counts decoded bytes
+		if err := tu.Unread(int64(len(tt.want))); err != nil {
+			t.Errorf("%s: Unread(%d) = %v", tt.name, len(tt.want), err)
+		}
+	}
+}
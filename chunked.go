@@ -0,0 +1,85 @@
+package unreader
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+)
+
+// Dechunk returns a new Unreader, configured by opts, that reads the body
+// after the cursor decoded from HTTP/1.1 chunked transfer coding, so that
+// the decoded payload can be sniffed and rewound. The new Unreader's
+// cursor, unreads and offsets all work on payload bytes. Chunk extensions
+// are ignored, and framing errors are returned as ErrMalformed. Decoding
+// stops after the last chunk, leaving u's cursor at the trailer section;
+// until then, u must not be read directly.
+func (u *Unreader) Dechunk(opts ...Option) (*Unreader, error) {
+	return New(&chunkedReader{u: u}, opts...)
+}
+
+// chunkedReader decodes chunked transfer coding read from u.
+type chunkedReader struct {
+	u       *Unreader
+	left    int64 // bytes left in the current chunk
+	started bool  // a chunk has been read, so a CRLF ends it
+	err     error
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for c.err == nil && c.left == 0 {
+		c.err = c.next()
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := c.u.Read(p[:min(int64(len(p)), c.left)])
+	c.left -= int64(n)
+	if n > 0 {
+		return n, nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return 0, err
+}
+
+// next reads up to the start of the next chunk's data, returning io.EOF
+// after the last chunk.
+func (c *chunkedReader) next() error {
+	if c.started {
+		b, err := c.u.Peek(2)
+		if len(b) < 2 {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if b[0] != '\r' || b[1] != '\n' {
+			return ErrMalformed
+		}
+		c.u.Discard(2)
+	}
+	c.started = true
+	line, err := c.u.ReadLine()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if i := bytes.IndexByte(line, ';'); i >= 0 {
+		line = line[:i]
+	}
+	n, err := strconv.ParseUint(string(bytes.TrimRight(line, " \t")), 16, 63)
+	if err != nil {
+		return ErrMalformed
+	}
+	if n == 0 {
+		return io.EOF
+	}
+	c.left = int64(n)
+	return nil
+}
@@ -0,0 +1,58 @@
+package unreader
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDechunk(t *testing.T) {
+	tests := []struct {
+		in   string
+		body string
+		err  error
+		rest string
+	}{
+		{"5\r\nhello\r\n7;ext=1\r\n, world\r\n0\r\nX-Sum: 1\r\n\r\n", "hello, world", nil, "X-Sum: 1\r\n\r\n"},
+		{"A\r\n0123456789\r\n0\r\n\r\nnext", "0123456789", nil, "\r\nnext"},
+		{"0\r\n\r\n", "", nil, "\r\n"},
+		{"5\r\nhel", "hel", io.ErrUnexpectedEOF, ""},
+		{"5\r\nhelloXX3\r\nabc\r\n0\r\n\r\n", "hello", ErrMalformed, ""},
+		{"zz\r\nhello\r\n", "", ErrMalformed, ""},
+		{"5\r\nhello\r\n", "hello", io.ErrUnexpectedEOF, ""},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(64, strings.NewReader(tt.in))
+		d, err := u.Dechunk(WithBufferSize(64))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(d)
+		if string(body) != tt.body || !errors.Is(err, tt.err) {
+			t.Errorf("Dechunk(%q) = %q, %v; want %q, %v", tt.in, body, err, tt.body, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("Dechunk(%q) left %q, want %q", tt.in, rest, tt.rest)
+		}
+	}
+}
+
+func TestDechunkUnread(t *testing.T) {
+	u, _ := NewUnreader(64, strings.NewReader("3\r\nabc\r\n3\r\ndef\r\n0\r\n\r\n"))
+	d, _ := u.Dechunk()
+	if b, err := d.Peek(5); err != nil || string(b) != "abcde" {
+		t.Fatalf("Peek = %q, %v", b, err)
+	}
+	d.Discard(5)
+	if err := d.Unread(4); err != nil || d.Cursor() != 1 {
+		t.Fatalf("Unread = %v, cursor %d", err, d.Cursor())
+	}
+	if b, _ := io.ReadAll(d); string(b) != "bcdef" {
+		t.Fatalf("ReadAll = %q", b)
+	}
+}
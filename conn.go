@@ -0,0 +1,52 @@
+package unreader
+
+import (
+	"net"
+	"time"
+)
+
+// Conn is a net.Conn whose reads go through an Unreader, so bytes read from
+// the connection can be unread, peeked and replayed before the connection
+// is handed to code that expects a net.Conn. Writes, deadlines and
+// addresses go straight to the underlying connection, which is also
+// available as the Conn field.
+type Conn struct {
+	*Unreader
+	net.Conn
+}
+
+// WrapConn returns a Conn reading c through an Unreader with a buffer of
+// size bytes.
+func WrapConn(c net.Conn, size int64) (*Conn, error) {
+	u, err := NewUnreader(size, c)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Unreader: u, Conn: c}, nil
+}
+
+// Read reads through the Unreader, replaying unread bytes first.
+func (c *Conn) Read(p []byte) (int, error) {
+	return c.Unreader.Read(p)
+}
+
+// Close closes the Unreader, which closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.Unreader.Close()
+}
+
+// SetDeadline sets the read and write deadlines of the connection,
+// remembering the read deadline for the Unreader's timed reads.
+func (c *Conn) SetDeadline(t time.Time) error {
+	if err := c.Conn.SetDeadline(t); err != nil {
+		return err
+	}
+	c.Unreader.deadline = t
+	return nil
+}
+
+// SetReadDeadline sets the read deadline of the connection, remembering it
+// for the Unreader's timed reads.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.Unreader.SetReadDeadline(t)
+}
@@ -0,0 +1,54 @@
+package unreader
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+func TestWrapConn(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Write([]byte("SSH-2.0-x\r\n"))
+		buf := make([]byte, 4)
+		io.ReadFull(c, buf)
+		c.Write(buf)
+		c.Close()
+	}()
+	raw, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := WrapConn(raw, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nc net.Conn = c
+	if i := c.Detect(MatchSSH()); i != 0 || c.Cursor() != 0 {
+		t.Fatalf("Detect = %d, cursor %d", i, c.Cursor())
+	}
+	if _, err := nc.Write([]byte("pong")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(nc)
+	if err != nil || string(got) != "SSH-2.0-x\r\npong" {
+		t.Fatalf("ReadAll = %q, %v", got, err)
+	}
+	if nc.RemoteAddr() == nil {
+		t.Fatal("no remote address")
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	if _, err := nc.Read(make([]byte, 1)); err != ErrClosed {
+		t.Fatalf("Read after Close = %v", err)
+	}
+}
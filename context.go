@@ -0,0 +1,142 @@
+package unreader
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// aLongTimeAgo is a read deadline in the past, used to interrupt a blocked
+// read when a context ends.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// ReadContext is like Read, but returns ctx.Err() if ctx ends before the
+// underlying reader returns.
+func (u *Unreader) ReadContext(ctx context.Context, p []byte) (n int, err error) {
+	err = u.withContext(ctx, func() (err error) {
+		n, err = u.Read(p)
+		return err
+	})
+	return n, err
+}
+
+// PeekContext is like Peek, but returns the bytes buffered so far and
+// ctx.Err() if ctx ends before n bytes arrive.
+func (u *Unreader) PeekContext(ctx context.Context, n int) (b []byte, err error) {
+	err = u.withContext(ctx, func() (err error) {
+		b, err = u.Peek(n)
+		return err
+	})
+	return b, err
+}
+
+// ReadUntilContext is like ReadUntil, but stops with ctx.Err() if ctx ends
+// before delim arrives. As with other errors, the bytes read up to then are
+// returned and can be unread with UnreadToken.
+func (u *Unreader) ReadUntilContext(ctx context.Context, delim []byte, skip bool) (b []byte, err error) {
+	err = u.withContext(ctx, func() (err error) {
+		b, err = u.ReadUntil(delim, skip)
+		return err
+	})
+	return b, err
+}
+
+// withContext runs f, interrupting reads of the underlying reader when ctx
+// ends. Readers with read deadlines are interrupted by moving the deadline
+// into the past; others are read by a watchdogReader.
+func (u *Unreader) withContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		return f()
+	}
+	// wrapping readers such as transform.Reader keep errors, so only an
+	// unwrapped reader can be interrupted with a deadline
+	if rd, ok := u.src.(readDeadliner); ok && u.rd == u.src {
+		fired := make(chan struct{})
+		stop := context.AfterFunc(ctx, func() {
+			rd.SetReadDeadline(aLongTimeAgo)
+			close(fired)
+		})
+		err := f()
+		if !stop() {
+			<-fired
+			rd.SetReadDeadline(u.deadline)
+			if err != nil {
+				err = ctx.Err()
+			}
+		}
+		return err
+	}
+	w, ok := u.rd.(*watchdogReader)
+	if !ok {
+		w = &watchdogReader{r: u.rd}
+		u.rd = w
+	}
+	w.ctx = ctx
+	err := f()
+	w.ctx = nil
+	return err
+}
+
+// watchdogReader reads r in a goroutine while a context is set, so that
+// waiting for a read can be abandoned when the context ends. The abandoned
+// read keeps going, and its result is returned by the next Read.
+type watchdogReader struct {
+	r   io.Reader
+	ctx context.Context // context of the current read, or nil to wait
+	res chan readResult // result of the read in flight, or nil
+	buf []byte          // bytes left from a finished read
+	err error           // error left from a finished read
+}
+
+type readResult struct {
+	b   []byte
+	err error
+}
+
+func (w *watchdogReader) Read(p []byte) (int, error) {
+	if len(w.buf) == 0 && w.err == nil {
+		if w.res == nil && w.ctx == nil {
+			return w.r.Read(p)
+		}
+		if w.res == nil {
+			res := make(chan readResult, 1)
+			b := make([]byte, len(p))
+			go func() {
+				n, err := w.r.Read(b)
+				res <- readResult{b[:n], err}
+			}()
+			w.res = res
+		}
+		var done <-chan struct{}
+		if w.ctx != nil {
+			done = w.ctx.Done()
+		}
+		select {
+		case r := <-w.res:
+			w.res = nil
+			w.buf, w.err = r.b, r.err
+		case <-done:
+			return 0, w.ctx.Err()
+		}
+	}
+	n := copy(p, w.buf)
+	w.buf = w.buf[n:]
+	if len(w.buf) == 0 && w.err != nil {
+		err := w.err
+		w.err = nil
+		return n, err
+	}
+	return n, nil
+}
+
+// Close closes r if it implements io.Closer, which also ends a read in
+// flight for most readers.
+func (w *watchdogReader) Close() error {
+	if c, ok := w.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package unreader
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReadContextDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	c, err := WrapConn(server, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.PeekContext(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("PeekContext = %v, want context.DeadlineExceeded", err)
+	}
+
+	go client.Write([]byte("ab\ncd"))
+	b, err := c.ReadUntilContext(context.Background(), []byte("\n"), true)
+	if err != nil || string(b) != "ab" {
+		t.Fatalf("ReadUntilContext = %q, %v", b, err)
+	}
+}
+
+func TestReadContextWatchdog(t *testing.T) {
+	pr, pw := io.Pipe()
+	u, _ := NewUnreader(64, pr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	p := make([]byte, 8)
+	if _, err := u.ReadContext(ctx, p); err != context.Canceled {
+		t.Fatalf("ReadContext = %v, want context.Canceled", err)
+	}
+
+	// the abandoned read's bytes aren't lost
+	go pw.Write([]byte("hello"))
+	b, err := u.Peek(5)
+	if err != nil || string(b) != "hello" {
+		t.Fatalf("Peek = %q, %v", b, err)
+	}
+	pw.Close()
+	if _, err := u.Discard(5); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := u.ReadContext(context.Background(), p); n != 0 || err != io.EOF {
+		t.Fatalf("ReadContext at end = %d, %v", n, err)
+	}
+}
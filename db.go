@@ -0,0 +1,126 @@
+package unreader
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Request codes a PostgreSQL client can send in place of a protocol version
+// in its first message.
+const (
+	pgProtocol3     = 3 << 16
+	pgCancelRequest = 80877102
+	pgSSLRequest    = 80877103
+	pgGSSENCRequest = 80877104
+)
+
+// maxStartupPacket is the largest startup message PostgreSQL accepts.
+const maxStartupPacket = 10000
+
+// postgresCode returns the protocol version or request code of the
+// PostgreSQL startup message at the cursor, or 0 if there isn't one.
+func (u *Unreader) postgresCode() uint32 {
+	b := u.peekValid(8, isPostgresPrefix)
+	if len(b) < 8 {
+		return 0
+	}
+	size := binary.BigEndian.Uint32(b)
+	code := binary.BigEndian.Uint32(b[4:])
+	switch code {
+	case pgSSLRequest, pgGSSENCRequest:
+		if size == 8 {
+			return code
+		}
+	case pgCancelRequest:
+		if size == 16 {
+			return code
+		}
+	case pgProtocol3:
+		if size > 8 && size <= maxStartupPacket {
+			return code
+		}
+	}
+	return 0
+}
+
+// pgCodes are the first 4 bytes of the codes postgresCode recognizes.
+var pgCodes = [][]byte{
+	{0x00, 0x03, 0x00, 0x00},
+	{0x04, 0xd2, 0x16, 0x2e},
+	{0x04, 0xd2, 0x16, 0x2f},
+	{0x04, 0xd2, 0x16, 0x30},
+}
+
+// isPostgresPrefix reports whether b, of up to 8 bytes, could begin a
+// startup message: a length no more than maxStartupPacket followed by one
+// of pgCodes.
+func isPostgresPrefix(b []byte) bool {
+	for i, c := range b[:min(len(b), 3)] {
+		if i < 2 && c != 0 || i == 2 && c > maxStartupPacket>>8 {
+			return false
+		}
+	}
+	if len(b) <= 4 {
+		return true
+	}
+	for _, code := range pgCodes {
+		if bytes.HasPrefix(code, b[4:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPostgres reports whether the stream starts with a PostgreSQL client's
+// first message: a protocol 3.0 startup message, an SSLRequest, a
+// GSSENCRequest or a CancelRequest. Nothing is consumed.
+func (u *Unreader) IsPostgres() bool {
+	return u.postgresCode() != 0
+}
+
+// IsPostgresSSLRequest reports whether the stream starts with a PostgreSQL
+// SSLRequest, sent by clients that want to switch to TLS before their
+// startup message. Nothing is consumed.
+func (u *Unreader) IsPostgresSSLRequest() bool {
+	return u.postgresCode() == pgSSLRequest
+}
+
+// MatchPostgres returns a Matcher for streams from PostgreSQL clients, as
+// reported by IsPostgres.
+func MatchPostgres() Matcher {
+	return MatcherFunc((*Unreader).IsPostgres)
+}
+
+// IsMySQL reports whether the stream starts with the initial handshake
+// packet of a MySQL server, protocol version 10, without consuming
+// anything. MySQL servers speak first, so this recognizes servers, such as
+// on an outbound connection; clients send nothing until they're greeted.
+func (u *Unreader) IsMySQL() bool {
+	b := u.peekValid(5, isMySQLPrefix)
+	if len(b) < 5 || !isMySQLPrefix(b) || b[0] < 2 {
+		return false
+	}
+	// the server version is NUL-terminated within the packet
+	b = u.peekQuiet(4 + int(b[0]))
+	return len(b) > 5 && bytes.IndexByte(b[5:], 0) >= 0
+}
+
+// isMySQLPrefix reports whether b, of up to 5 bytes, could begin an
+// initial handshake packet. Its length is checked to be under 256 bytes,
+// which any real greeting is, so that a client's short first message is
+// rejected without waiting for more.
+func isMySQLPrefix(b []byte) bool {
+	want := []byte{0, 0, 0, 0, 10}
+	for i, c := range b {
+		if i > 0 && c != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchMySQL returns a Matcher for streams from MySQL servers, as reported
+// by IsMySQL.
+func MatchMySQL() Matcher {
+	return MatcherFunc((*Unreader).IsMySQL)
+}
@@ -0,0 +1,80 @@
+package unreader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsPostgres(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		postgres bool
+		ssl      bool
+	}{
+		{"SSLRequest", "\x00\x00\x00\x08\x04\xd2\x16\x2f", true, true},
+		{"GSSENCRequest", "\x00\x00\x00\x08\x04\xd2\x16\x30", true, false},
+		{"CancelRequest", "\x00\x00\x00\x10\x04\xd2\x16\x2e\x00\x00\x00\x01\x00\x00\x00\x02", true, false},
+		{"startup", "\x00\x00\x00\x10\x00\x03\x00\x00user\x00bob\x00\x00", true, false},
+		{"SSLRequest with bad size", "\x00\x00\x00\x09\x04\xd2\x16\x2f", false, false},
+		{"startup too large", "\x00\x01\x00\x00\x00\x03\x00\x00", false, false},
+		{"protocol 2", "\x00\x00\x00\x10\x00\x02\x00\x00", false, false},
+		{"short", "\x00\x00\x00\x08", false, false},
+		{"http", "GET / HTTP/1.1\r\n", false, false},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(64, strings.NewReader(tt.in))
+		if got := u.IsPostgres(); got != tt.postgres {
+			t.Errorf("%s: IsPostgres() = %v, want %v", tt.name, got, tt.postgres)
+		}
+		if got := u.IsPostgresSSLRequest(); got != tt.ssl {
+			t.Errorf("%s: IsPostgresSSLRequest() = %v, want %v", tt.name, got, tt.ssl)
+		}
+		if u.Cursor() != 0 {
+			t.Errorf("%s: cursor moved to %d", tt.name, u.Cursor())
+		}
+	}
+}
+
+func TestIsMySQL(t *testing.T) {
+	for in, want := range map[string]bool{
+		"\x0e\x00\x00\x00\x0a8.0.36\x00\x01\x02\x03\x04": true,
+		"\x0e\x00\x00\x00\x098.0.36\x00\x01\x02\x03\x04": false,
+		"\x0e\x00\x00\x01\x0a8.0.36\x00\x01\x02\x03\x04": false,
+		"\x0e\x00\x00\x00\x0a8.0.36":                     false,
+		"\x01\x00\x00\x00\x0a":                           false,
+		"GET / HTTP/1.1\r\n":                             false,
+	} {
+		u, _ := NewUnreader(64, strings.NewReader(in))
+		if got := u.IsMySQL(); got != want || u.Cursor() != 0 {
+			t.Errorf("IsMySQL(%q) = %v with cursor at %d, want %v", in, got, u.Cursor(), want)
+		}
+	}
+}
+
+func TestDetectDatabase(t *testing.T) {
+	for in, want := range map[string]int{
+		"\x00\x00\x00\x08\x04\xd2\x16\x2f":               0,
+		"\x0e\x00\x00\x00\x0a8.0.36\x00\x01\x02\x03\x04": 1,
+		"GET / HTTP/1.1\r\n":                             -1,
+	} {
+		u, _ := NewUnreader(64, strings.NewReader(in))
+		if i := u.Detect(MatchPostgres(), MatchMySQL()); i != want {
+			t.Errorf("Detect(%q) = %d, want %d", in, i, want)
+		}
+	}
+}
+
+func TestDatabaseSilentPeer(t *testing.T) {
+	for _, in := range [][]byte{{5, 1, 0}, {0x16, 3, 1}, []byte("GET"), {4, 1, 0, 80}} {
+		for name, m := range map[string]Matcher{"postgres": MatchPostgres(), "mysql": MatchMySQL()} {
+			u := silentPeer(t, in)
+			got := true
+			if !returns(func() { got = m.Match(u) }) {
+				t.Errorf("%s: Match(% x) blocked", name, in)
+			} else if got {
+				t.Errorf("%s: Match(% x) = true", name, in)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package unreader
+
+import "time"
+
+// readDeadliner is implemented by underlying readers, such as net.Conn,
+// whose reads can be given a deadline.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// SetReadDeadline sets the read deadline of the underlying reader and
+// remembers it, so that PeekTimeout and FillTimeout can restore it. A zero
+// t means reads don't time out. It returns ErrNoDeadline if the underlying
+// reader doesn't support read deadlines.
+func (u *Unreader) SetReadDeadline(t time.Time) error {
+	rd, ok := u.src.(readDeadliner)
+	if !ok {
+		return ErrNoDeadline
+	}
+	if err := rd.SetReadDeadline(t); err != nil {
+		return err
+	}
+	u.deadline = t
+	return nil
+}
+
+// withTimeout runs f with the underlying reader's read deadline at most d
+// away, then restores the deadline set with SetReadDeadline. Wrapping
+// readers such as transform.Reader keep a timeout as a permanent error, so
+// only an unwrapped reader can be timed out.
+func (u *Unreader) withTimeout(d time.Duration, f func() error) error {
+	rd, ok := u.src.(readDeadliner)
+	if !ok || u.rd != u.src {
+		return ErrNoDeadline
+	}
+	t := time.Now().Add(d)
+	if !u.deadline.IsZero() && u.deadline.Before(t) {
+		t = u.deadline
+	}
+	if err := rd.SetReadDeadline(t); err != nil {
+		return err
+	}
+	err := f()
+	if derr := rd.SetReadDeadline(u.deadline); err == nil {
+		err = derr
+	}
+	return err
+}
+
+// PeekTimeout is like Peek, but waits at most d for the underlying reader,
+// so that protocol detection can't hang on a silent peer. Bytes that arrive
+// in time stay buffered. A timeout is returned as the reader's error, which
+// for a net.Conn wraps os.ErrDeadlineExceeded. If the bytes are already
+// buffered the underlying reader isn't touched; otherwise PeekTimeout
+// returns ErrNoDeadline if the reader doesn't support read deadlines, or if
+// it's wrapped by a transform or BOM stripping, which would keep the timeout
+// as the error for every later read.
+func (u *Unreader) PeekTimeout(n int, d time.Duration) ([]byte, error) {
+	if n >= 0 && u.bytesRead-u.cursor >= int64(n) {
+		return u.Peek(n)
+	}
+	var b []byte
+	err := u.withTimeout(d, func() (err error) {
+		b, err = u.Peek(n)
+		return err
+	})
+	return b, err
+}
+
+// FillTimeout reads once from the underlying reader into the buffer without
+// moving the cursor, waiting at most d, and returns the number of bytes
+// added. It reads no more than fits without evicting unread bytes, and
+// returns ErrBufferFull if none fit. Errors are as for PeekTimeout.
+func (u *Unreader) FillTimeout(d time.Duration) (n int, err error) {
+	free := u.cb.Size() - (u.bytesRead - u.cursor)
+	if free <= 0 {
+		return 0, ErrBufferFull
+	}
+	err = u.withTimeout(d, func() (err error) {
+		n, err = u.fill(int(min(free, fillSize)))
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,68 @@
+package unreader
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeekTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	c, err := WrapConn(server, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.PeekTimeout(3, 10*time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("PeekTimeout on silent peer = %v", err)
+	}
+	if _, err := c.FillTimeout(10 * time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("FillTimeout on silent peer = %v", err)
+	}
+
+	// the deadline is restored, so a plain read can wait for the peer
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		client.Write([]byte("abc"))
+	}()
+	b, err := c.Peek(3)
+	if err != nil || string(b) != "abc" {
+		t.Fatalf("Peek = %q, %v", b, err)
+	}
+	b, err = c.PeekTimeout(3, time.Nanosecond)
+	if err != nil || string(b) != "abc" {
+		t.Fatalf("PeekTimeout of buffered bytes = %q, %v", b, err)
+	}
+}
+
+func TestPeekTimeoutNoDeadline(t *testing.T) {
+	u, _ := NewUnreader(16, strings.NewReader("abc"))
+	if _, err := u.PeekTimeout(1, time.Second); err != ErrNoDeadline {
+		t.Fatalf("PeekTimeout = %v, want ErrNoDeadline", err)
+	}
+	if err := u.SetReadDeadline(time.Now()); err != ErrNoDeadline {
+		t.Fatalf("SetReadDeadline = %v, want ErrNoDeadline", err)
+	}
+}
+
+func TestPeekTimeoutWrapped(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	u, err := New(server, WithNewlineNormalization(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.PeekTimeout(3, 10*time.Millisecond); err != ErrNoDeadline {
+		t.Fatalf("PeekTimeout through a transform = %v, want ErrNoDeadline", err)
+	}
+	go client.Write([]byte("ab\r\n"))
+	if b, err := u.Peek(3); err != nil || string(b) != "ab\n" {
+		t.Fatalf("Peek after PeekTimeout = %q, %v", b, err)
+	}
+}
@@ -0,0 +1,158 @@
+package unreader
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// runeEncoding returns the encoding ReadRune and PeekRune decode: the one
+// set with WithRuneEncoding, else one indicated by a stripped byte order
+// mark if no transformers already decode it, else UTF-8.
+func (u *Unreader) runeEncoding() Encoding {
+	if u.runeEnc != EncodingUnknown {
+		return u.runeEnc
+	}
+	if u.bom != nil && len(u.transforms) == 0 {
+		if enc := u.BOM(); enc != EncodingUnknown {
+			return enc
+		}
+	}
+	return UTF8
+}
+
+func byteOrder(enc Encoding) binary.ByteOrder {
+	if enc == UTF16LE || enc == UTF32LE {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// fullRune reports whether b begins with a full rune in enc, or with bytes
+// that can't be the start of one.
+func fullRune(b []byte, enc Encoding) bool {
+	switch enc {
+	case UTF16BE, UTF16LE:
+		if len(b) < 2 {
+			return false
+		}
+		r1 := rune(byteOrder(enc).Uint16(b))
+		return len(b) >= 4 || r1 < 0xD800 || r1 >= 0xDC00
+	case UTF32BE, UTF32LE:
+		return len(b) >= 4
+	}
+	return utf8.FullRune(b)
+}
+
+// decodeRune decodes the first rune of b in enc. Invalid or truncated input
+// decodes as utf8.RuneError with the size of the bad code unit and ok false,
+// which tells it apart from an encoded U+FFFD.
+func decodeRune(b []byte, enc Encoding) (r rune, size int, ok bool) {
+	switch enc {
+	case UTF16BE, UTF16LE:
+		if len(b) < 2 {
+			return utf8.RuneError, len(b), false
+		}
+		order := byteOrder(enc)
+		r1 := rune(order.Uint16(b))
+		if !utf16.IsSurrogate(r1) {
+			return r1, 2, true
+		}
+		if len(b) < 4 || r1 >= 0xDC00 {
+			return utf8.RuneError, 2, false
+		}
+		r = utf16.DecodeRune(r1, rune(order.Uint16(b[2:])))
+		if r == utf8.RuneError {
+			return r, 2, false
+		}
+		return r, 4, true
+	case UTF32BE, UTF32LE:
+		if len(b) < 4 {
+			return utf8.RuneError, len(b), false
+		}
+		r = rune(byteOrder(enc).Uint32(b))
+		if !utf8.ValidRune(r) {
+			return utf8.RuneError, 4, false
+		}
+		return r, 4, true
+	}
+	r, size = utf8.DecodeRune(b)
+	return r, size, r != utf8.RuneError || size > 1
+}
+
+// decodeLastRune decodes the last rune of b in enc, like decodeRune.
+func decodeLastRune(b []byte, enc Encoding) (r rune, size int) {
+	switch enc {
+	case UTF16BE, UTF16LE:
+		if len(b) < 2 {
+			return utf8.RuneError, len(b)
+		}
+		order := byteOrder(enc)
+		r2 := rune(order.Uint16(b[len(b)-2:]))
+		if r2 < 0xDC00 || r2 > 0xDFFF || len(b) < 4 {
+			r, size, _ = decodeRune(b[len(b)-2:], enc)
+			return r, size
+		}
+		if r, size, _ = decodeRune(b[len(b)-4:], enc); size == 4 {
+			return r, size
+		}
+		return utf8.RuneError, 2
+	case UTF32BE, UTF32LE:
+		if len(b) < 4 {
+			return utf8.RuneError, len(b)
+		}
+		r, size, _ = decodeRune(b[len(b)-4:], enc)
+		return r, size
+	}
+	return utf8.DecodeLastRune(b)
+}
+
+// countRunes counts the runes starting in p, which begins at stream offset
+// base. Bytes are counted one at a time, so a rune split across calls is
+// counted once, in the call holding its first byte: for UTF-8 that's every
+// byte but continuation bytes, for UTF-16 every code unit but low
+// surrogates, and for UTF-32 every fourth byte.
+func countRunes(p []byte, base int64, enc Encoding) (n int64) {
+	for i, c := range p {
+		off := base + int64(i)
+		switch enc {
+		case UTF16BE:
+			if off%2 == 0 && (c < 0xDC || c > 0xDF) {
+				n++
+			}
+		case UTF16LE:
+			if off%2 == 1 && (c < 0xDC || c > 0xDF) {
+				n++
+			}
+		case UTF32BE, UTF32LE:
+			if off%4 == 0 {
+				n++
+			}
+		default:
+			if c&0xC0 != 0x80 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// RunesRead returns the number of runes before the cursor, in the encoding
+// ReadRune decodes. Like Position, it stays correct across unreads. Invalid
+// UTF-8 lead bytes count as one rune each, but stray continuation bytes
+// aren't counted.
+func (u *Unreader) RunesRead() int64 {
+	return u.runes - countRunes(u.replay(), u.cursor, u.countEncoding())
+}
+
+// countEncoding is like runeEncoding, but never reads from the underlying
+// reader to detect a byte order mark.
+func (u *Unreader) countEncoding() Encoding {
+	if u.runeEnc != EncodingUnknown {
+		return u.runeEnc
+	}
+	if u.bom != nil && len(u.transforms) == 0 && u.bom.done && u.bom.enc != EncodingUnknown {
+		return u.bom.enc
+	}
+	return UTF8
+}
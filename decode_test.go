@@ -0,0 +1,25 @@
+package unreader
+
+import (
+	"strings"
+	"testing"
+
+	textunicode "golang.org/x/text/encoding/unicode"
+)
+
+func TestReadRuneBOMTransform(t *testing.T) {
+	dec := textunicode.UTF16(textunicode.LittleEndian, textunicode.UseBOM).NewDecoder()
+	u, err := New(strings.NewReader("\xff\xfeh\x00i\x00"), WithBOMStripping(true), WithTransform(dec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range "hi" {
+		r, _, err := u.ReadRune()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r != want {
+			t.Errorf("ReadRune() = %q, want %q", r, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package unreader
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		"zlib": func(r io.Reader) (io.Reader, error) {
+			return zlib.NewReader(r)
+		},
+		"bzip2": func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		},
+	}
+
+	// compressedFormats are the built-in formats DetectFormat reports that
+	// Decompress must not pass through as they are.
+	compressedFormats = map[string]bool{
+		"gzip": true, "zlib": true, "bzip2": true, "xz": true, "zstd": true,
+	}
+)
+
+// RegisterDecompressor makes Decompress use open to decompress the format
+// with the given name, as reported by DetectFormat. gzip, zlib and bzip2
+// are built in; others, such as "zstd", can be added with a third-party
+// package:
+//
+//	unreader.RegisterDecompressor("zstd", func(r io.Reader) (io.Reader, error) {
+//		return zstd.NewReader(r)
+//	})
+func RegisterDecompressor(format string, open func(io.Reader) (io.Reader, error)) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	decompressors[format] = open
+}
+
+// Decompress detects whether the stream after the cursor is compressed in
+// a format with a registered decompressor, and returns a new Unreader,
+// configured by opts, that reads the decompressed stream, along with the
+// name of the format. Its cursor, unreads and offsets all work on
+// decompressed bytes. If the stream isn't compressed, the new Unreader reads
+// it as it is and the format is "". A stream compressed in a format with no
+// registered decompressor, such as "zstd", gives an error wrapping
+// ErrNoDecompressor. u must not be read directly afterwards.
+func (u *Unreader) Decompress(opts ...Option) (*Unreader, string, error) {
+	format, err := u.DetectFormat()
+	if err != nil {
+		return nil, "", err
+	}
+	if format == "" && u.isZlib() {
+		format = "zlib"
+	}
+	decompressorsMu.RLock()
+	open, ok := decompressors[format]
+	decompressorsMu.RUnlock()
+	if !ok && compressedFormats[format] {
+		return nil, format, fmt.Errorf("%w: %s", ErrNoDecompressor, format)
+	}
+	if !ok {
+		nu, err := New(u, opts...)
+		return nu, "", err
+	}
+	r, err := open(u)
+	if err != nil {
+		return nil, "", err
+	}
+	nu, err := New(r, opts...)
+	return nu, format, err
+}
+
+// isZlib reports whether the next two bytes are a zlib header, which has no
+// fixed magic but a checksum over a known compression method.
+func (u *Unreader) isZlib() bool {
+	b := u.peekQuiet(2)
+	return len(b) == 2 && b[0]&0x0f == 8 && b[0]>>4 <= 7 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
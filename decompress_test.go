@@ -0,0 +1,42 @@
+package unreader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecompress(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("hello gzip"))
+	w.Close()
+	for _, c := range []struct{ in, format, want string }{
+		{gz.String(), "gzip", "hello gzip"},
+		{"plain text", "", "plain text"},
+	} {
+		src, _ := NewUnreader(64, strings.NewReader(c.in))
+		u, f, err := src.Decompress(WithBufferSize(32))
+		if err != nil || f != c.format {
+			t.Fatalf("Decompress() = %q, %v, want %q", f, err, c.format)
+		}
+		all, _ := io.ReadAll(u)
+		if string(all) != c.want {
+			t.Errorf("read %q, want %q", all, c.want)
+		}
+	}
+}
+
+func TestDecompressUnregistered(t *testing.T) {
+	src := NewUnreaderString("\x28\xb5\x2f\xfd\x00\x00")
+	_, f, err := src.Decompress()
+	if !errors.Is(err, ErrNoDecompressor) || f != "zstd" {
+		t.Fatalf("Decompress() = %q, %v, want zstd, ErrNoDecompressor", f, err)
+	}
+	if !strings.Contains(err.Error(), "zstd") {
+		t.Errorf("error %q doesn't name the format", err)
+	}
+}
@@ -0,0 +1,67 @@
+package unreader
+
+import "encoding/binary"
+
+// dnsHeaderLen is the length of the fixed header of a DNS message.
+const dnsHeaderLen = 12
+
+// DNSHeader is the fixed header of a DNS message, as defined in RFC 1035
+// section 4.1.1.
+type DNSHeader struct {
+	ID       uint16
+	Flags    uint16 // the whole flags field, including the ones below
+	Response bool   // QR bit: the message is a response
+	Opcode   uint8
+	Rcode    uint8
+	QDCount  uint16 // questions
+	ANCount  uint16 // answers
+	NSCount  uint16 // authority records
+	ARCount  uint16 // additional records
+}
+
+// ReadDNSMessage reads a DNS message framed for TCP by a 2-byte big-endian
+// length prefix, and returns a copy of the message without the prefix.
+// UnreadToken pushes back the message and its prefix. A message shorter
+// than its header returns ErrMalformed. Otherwise errors are as for
+// ReadFrame: if the message isn't complete, nothing is consumed and an
+// *IncompleteError reports how many more bytes are needed.
+func (u *Unreader) ReadDNSMessage() ([]byte, error) {
+	frame, err := u.ReadFrame(2, func(h []byte) (int, error) {
+		n := int(binary.BigEndian.Uint16(h))
+		if n < dnsHeaderLen {
+			return 0, ErrMalformed
+		}
+		return n, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return frame[2:], nil
+}
+
+// PeekDNSHeader decodes the header of the next TCP-framed DNS message
+// without consuming anything, so that messages can be routed by QR bit or
+// opcode before they are read whole. Errors are as for ReadDNSMessage.
+func (u *Unreader) PeekDNSHeader() (DNSHeader, error) {
+	const size = 2 + dnsHeaderLen
+	b, err := u.Peek(size)
+	if len(b) < size {
+		return DNSHeader{}, incomplete(size, b, err)
+	}
+	if binary.BigEndian.Uint16(b) < dnsHeaderLen {
+		return DNSHeader{}, ErrMalformed
+	}
+	b = b[2:]
+	flags := binary.BigEndian.Uint16(b[2:])
+	return DNSHeader{
+		ID:       binary.BigEndian.Uint16(b),
+		Flags:    flags,
+		Response: flags&0x8000 != 0,
+		Opcode:   uint8(flags >> 11 & 0xf),
+		Rcode:    uint8(flags & 0xf),
+		QDCount:  binary.BigEndian.Uint16(b[4:]),
+		ANCount:  binary.BigEndian.Uint16(b[6:]),
+		NSCount:  binary.BigEndian.Uint16(b[8:]),
+		ARCount:  binary.BigEndian.Uint16(b[10:]),
+	}, nil
+}
@@ -0,0 +1,71 @@
+package unreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// dnsQuery is a TCP-framed query for example.com A, ID 0x1234, RD set.
+var dnsQuery = []byte{
+	0, 29,
+	0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0,
+	7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+	0, 1, 0, 1,
+}
+
+func TestReadDNSMessage(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		msg  []byte
+		need int
+		err  error
+	}{
+		{dnsQuery, dnsQuery[2:], 0, nil},
+		{dnsQuery[:20], nil, 11, io.ErrUnexpectedEOF},
+		{dnsQuery[:1], nil, 1, io.ErrUnexpectedEOF},
+		{nil, nil, 0, io.EOF},
+		{[]byte{0, 4, 1, 2, 3, 4}, nil, 0, ErrMalformed},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(512, bytes.NewReader(tt.in))
+		msg, err := u.ReadDNSMessage()
+		if string(msg) != string(tt.msg) || !errors.Is(err, tt.err) {
+			t.Errorf("ReadDNSMessage(% x) = % x, %v; want % x, %v", tt.in, msg, err, tt.msg, tt.err)
+			continue
+		}
+		var ie *IncompleteError
+		if errors.As(err, &ie) && ie.Need != tt.need {
+			t.Errorf("ReadDNSMessage(% x) needs %d, want %d", tt.in, ie.Need, tt.need)
+		}
+		if err != nil && u.Cursor() != 0 {
+			t.Errorf("ReadDNSMessage(% x) consumed %d bytes on error", tt.in, u.Cursor())
+		}
+	}
+}
+
+func TestPeekDNSHeader(t *testing.T) {
+	reply := append([]byte(nil), dnsQuery...)
+	reply[4], reply[5] = 0x81, 0x83 // QR, RD, RA, NXDOMAIN
+	reply[4] |= 2 << 3              // opcode STATUS
+	tests := []struct {
+		in   []byte
+		want DNSHeader
+		err  error
+	}{
+		{dnsQuery, DNSHeader{ID: 0x1234, Flags: 0x0100, QDCount: 1}, nil},
+		{reply, DNSHeader{ID: 0x1234, Flags: 0x9183, Response: true, Opcode: 2, Rcode: 3, QDCount: 1}, nil},
+		{dnsQuery[:13], DNSHeader{}, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(512, bytes.NewReader(tt.in))
+		h, err := u.PeekDNSHeader()
+		if h != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("PeekDNSHeader(% x) = %+v, %v; want %+v, %v", tt.in, h, err, tt.want, tt.err)
+		}
+		if u.Cursor() != 0 {
+			t.Errorf("PeekDNSHeader consumed %d bytes", u.Cursor())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package unreader
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnreadBeyondWritten is returned by Unread when asked to rewind past
+	// the first byte ever read.
+	ErrUnreadBeyondWritten = errors.New("unreader: unread past start of stream")
+
+	// ErrUnreadBeyondBuffer is returned by Unread when the bytes to rewind to
+	// have already been evicted from the buffer.
+	ErrUnreadBeyondBuffer = errors.New("unreader: unread past start of buffer")
+
+	// ErrAdvanceBeyondBuffered is returned by Advance when asked to move
+	// past the bytes waiting to be replayed.
+	ErrAdvanceBeyondBuffered = errors.New("unreader: advance past buffered bytes")
+
+	// ErrBufferFull is returned when an operation needs more bytes held at
+	// once than the buffer can fit.
+	ErrBufferFull = errors.New("unreader: buffer full")
+
+	// ErrEvicted is returned when an absolute offset is no longer held in the
+	// buffer.
+	ErrEvicted = errors.New("unreader: offset evicted from buffer")
+
+	// ErrNegativeCount is returned when a count argument is negative.
+	ErrNegativeCount = errors.New("unreader: negative count")
+
+	// ErrInvalidUnreadRune is returned by UnreadRune when the last operation
+	// was not a successful ReadRune.
+	ErrInvalidUnreadRune = errors.New("unreader: invalid use of UnreadRune")
+
+	// ErrNoMark is returned when a mark operation finds no matching mark.
+	ErrNoMark = errors.New("unreader: no such mark")
+
+	// ErrPinned is returned by reads that would evict bytes held by a pushed
+	// mark or open transaction from a buffer that can't grow.
+	ErrPinned = errors.New("unreader: read would evict pinned bytes")
+
+	// ErrNoTransaction is returned by Commit and Rollback when no
+	// speculative region is open.
+	ErrNoTransaction = errors.New("unreader: no open transaction")
+
+	// ErrBacktrack can be returned by a function passed to Try to roll back
+	// because the input didn't match, rather than because of a failure.
+	ErrBacktrack = errors.New("unreader: backtrack")
+
+	// ErrInvalidUnreadLine is returned by UnreadLine when the last operation
+	// was not a successful ReadLine.
+	ErrInvalidUnreadLine = errors.New("unreader: invalid use of UnreadLine")
+
+	// ErrInvalidUnreadToken is returned by UnreadToken when the last
+	// operation did not return a token.
+	ErrInvalidUnreadToken = errors.New("unreader: invalid use of UnreadToken")
+
+	// ErrInvalidRune is returned by ReadRune and PeekRune for invalid input
+	// when strict rune decoding is on.
+	ErrInvalidRune = errors.New("unreader: invalid rune encoding")
+
+	// ErrNoMatch is returned when the upcoming bytes don't begin what a read
+	// expects, such as an opening quote. Nothing is consumed.
+	ErrNoMatch = errors.New("unreader: input does not match")
+
+	// ErrTokenTooLong is returned by reads of a token longer than the limit
+	// set with WithMaxTokenSize or passed to the read.
+	ErrTokenTooLong = errors.New("unreader: token too long")
+
+	// ErrOverflow is returned when a variable-length integer is too long for
+	// the type it's read into.
+	ErrOverflow = errors.New("unreader: integer overflow")
+
+	// ErrInvalidFrame is returned by ReadFrame for a header length less than
+	// 1.
+	ErrInvalidFrame = errors.New("unreader: invalid frame header length")
+
+	// ErrInvalidTLV is returned by ReadTLV for a layout whose tag or length
+	// size isn't from 1 to 8 bytes.
+	ErrInvalidTLV = errors.New("unreader: invalid TLV layout")
+
+	// ErrMalformed is returned by protocol sniffers when the upcoming bytes
+	// start like the protocol but don't parse.
+	ErrMalformed = errors.New("unreader: malformed input")
+
+	// ErrNoDeadline is returned by timed reads when the underlying reader
+	// doesn't support read deadlines.
+	ErrNoDeadline = errors.New("unreader: underlying reader has no read deadline")
+
+	// ErrWouldBlock is returned by reads through AsyncUnreader.Do that need
+	// more bytes than the producer has buffered so far.
+	ErrWouldBlock = errors.New("unreader: read would block")
+
+	// ErrNoDecompressor is returned by Decompress for a compressed stream
+	// whose format has no registered decompressor.
+	ErrNoDecompressor = errors.New("unreader: no decompressor registered")
+
+	// ErrSeekEnd is returned by Seek with io.SeekEnd, since the length of
+	// the stream is unknown.
+	ErrSeekEnd = errors.New("unreader: seek from end not supported")
+
+	// ErrInvalidWhence is returned by Seek for a whence that isn't one of
+	// io.SeekStart, io.SeekCurrent or io.SeekEnd.
+	ErrInvalidWhence = errors.New("unreader: invalid whence")
+
+	// ErrNegativePosition is returned by Seek for an offset before the start
+	// of the stream.
+	ErrNegativePosition = errors.New("unreader: negative position")
+
+	// ErrClosed is returned by reads after Close.
+	ErrClosed = errors.New("unreader: read on closed unreader")
+)
+
+// UnreadError is returned by Unread when it can't rewind as far as asked. It
+// wraps ErrUnreadBeyondWritten or ErrUnreadBeyondBuffer and records how far
+// the cursor could have been rewound instead.
+type UnreadError struct {
+	Requested int64 // bytes asked to be unread
+	Max       int64 // most bytes that could be unread
+	Size      int64 // size of the buffer
+	Err       error
+}
+
+func (e *UnreadError) Error() string {
+	return fmt.Sprintf("%v: requested %d, max %d, buffer size %d", e.Err, e.Requested, e.Max, e.Size)
+}
+
+func (e *UnreadError) Unwrap() error {
+	return e.Err
+}
+
+// IncompleteError is returned by reads of a whole record, such as
+// ReadFrame, when the stream can't supply all of it yet. Nothing is
+// consumed, so the read can be retried once more data arrives. It wraps the
+// reason the read fell short, with io.EOF reported as io.ErrUnexpectedEOF.
+type IncompleteError struct {
+	Need int // bytes still needed to complete the record
+	Err  error
+}
+
+func (e *IncompleteError) Error() string {
+	return fmt.Sprintf("%v: %d more bytes needed", e.Err, e.Need)
+}
+
+func (e *IncompleteError) Unwrap() error {
+	return e.Err
+}
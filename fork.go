@@ -0,0 +1,49 @@
+package unreader
+
+import (
+	"slices"
+
+	"github.com/freb/circbuf"
+)
+
+// Fork returns a new Unreader that shares u's buffer and underlying reader
+// but has its own cursor, starting at u's, so that two passes can go over
+// the same stream without coordinating unreads. Bytes read by either one
+// are there for the other to read, and each pins the bytes after the
+// other's cursor, marks and transactions: reads that would evict them grow
+// a growable buffer or fail with ErrPinned, so neither can get more than a
+// buffer ahead of the other. Counters such as Position are the stream's.
+// Forks must not be used from different goroutines at once.
+func (u *Unreader) Fork() *Unreader {
+	f := &Unreader{
+		stream:      u.stream,
+		cursor:      u.cursor,
+		greedy:      u.greedy,
+		maxToken:    u.maxToken,
+		strictRunes: u.strictRunes,
+		lexStart:    u.cursor,
+	}
+	u.readers = append(u.readers, f)
+	return f
+}
+
+// detach gives u an empty stream of its own, configured like the one it
+// shares with its forks.
+func (u *Unreader) detach() {
+	s := u.stream
+	s.readers = slices.DeleteFunc(s.readers, func(r *Unreader) bool { return r == u })
+	cb, _ := circbuf.NewBuffer(s.cb.Size())
+	u.stream = &stream{
+		cb:         cb,
+		rd:         closedReader{},
+		src:        closedReader{},
+		recording:  s.recording,
+		growable:   s.growable,
+		stripBOM:   s.stripBOM,
+		transforms: s.transforms,
+		runeEnc:    s.runeEnc,
+		readers:    []*Unreader{u},
+	}
+	u.cursor = 0
+	u.clearLast()
+}
@@ -0,0 +1,81 @@
+package unreader
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFork(t *testing.T) {
+	data := "alpha beta gamma delta"
+	src := &closeReader{Reader: strings.NewReader(data)}
+	u, _ := NewUnreader(8, src)
+	f := u.Fork()
+
+	// alternate between a checksum pass and a parsing pass
+	h := crc32.NewIEEE()
+	var words []string
+	buf := make([]byte, 3)
+	for {
+		n, err := u.Read(buf)
+		h.Write(buf[:n])
+		w, ferr := f.ReadToken()
+		if ferr == nil {
+			words = append(words, string(w))
+		}
+		if err == io.EOF && ferr == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+	}
+	if h.Sum32() != crc32.ChecksumIEEE([]byte(data)) {
+		t.Error("checksum pass saw different bytes")
+	}
+	if strings.Join(words, " ") != data {
+		t.Errorf("parsing pass read %q", words)
+	}
+
+	if err := f.Close(); err != nil || src.closed {
+		t.Fatalf("closing a fork: %v, source closed %v", err, src.closed)
+	}
+	if err := u.Close(); err != nil || !src.closed {
+		t.Fatalf("closing the last fork: %v, source closed %v", err, src.closed)
+	}
+}
+
+func TestForkPins(t *testing.T) {
+	u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+	f := u.Fork()
+	b := make([]byte, 4)
+	if n, err := u.Read(b); n != 4 || err != nil {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+	// f hasn't read anything, so u can't evict the first bytes
+	if _, err := u.Read(b); err != ErrPinned {
+		t.Fatalf("Read ahead of fork = %v, want ErrPinned", err)
+	}
+	if n, err := f.Read(b); n != 4 || string(b) != "0123" || err != nil {
+		t.Fatalf("fork Read = %q, %v", b[:n], err)
+	}
+	if got, err := io.ReadAll(u); string(got) != "4567" || err != ErrPinned {
+		t.Fatalf("ReadAll after fork caught up = %q, %v", got, err)
+	}
+	if err := f.Unread(1); !errors.Is(err, ErrUnreadBeyondBuffer) {
+		t.Fatalf("fork Unread of evicted byte = %v", err)
+	}
+
+	g, _ := NewUnreader(4, strings.NewReader("abcdef"))
+	h := g.Fork()
+	h.Reset(bytes.NewReader([]byte("xyz")))
+	if got, _ := io.ReadAll(g); string(got) != "abcdef" {
+		t.Fatalf("ReadAll after fork Reset = %q", got)
+	}
+	if got, _ := io.ReadAll(h); string(got) != "xyz" {
+		t.Fatalf("ReadAll of Reset fork = %q", got)
+	}
+}
@@ -0,0 +1,72 @@
+package unreader
+
+import (
+	"bytes"
+	"sync"
+)
+
+// signature identifies a format by magic bytes at an offset.
+type signature struct {
+	name   string
+	offset int
+	magic  []byte
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = []signature{
+		{"gzip", 0, []byte("\x1f\x8b")},
+		{"bzip2", 0, []byte("BZh")},
+		{"xz", 0, []byte("\xfd7zXZ\x00")},
+		{"zstd", 0, []byte("\x28\xb5\x2f\xfd")},
+		{"zip", 0, []byte("PK\x03\x04")},
+		{"zip", 0, []byte("PK\x05\x06")},
+		{"7z", 0, []byte("7z\xbc\xaf\x27\x1c")},
+		{"tar", 257, []byte("ustar")},
+		{"png", 0, []byte("\x89PNG\r\n\x1a\n")},
+		{"jpeg", 0, []byte("\xff\xd8\xff")},
+		{"gif", 0, []byte("GIF87a")},
+		{"gif", 0, []byte("GIF89a")},
+		{"pdf", 0, []byte("%PDF-")},
+		{"sqlite", 0, []byte("SQLite format 3\x00")},
+		{"elf", 0, []byte("\x7fELF")},
+		{"wasm", 0, []byte("\x00asm")},
+	}
+)
+
+// RegisterFormat adds a format for DetectFormat to recognize by magic bytes
+// at offset from the start of the data. Formats registered later are
+// checked first, so a registered format can refine a built-in one. It is
+// safe to call concurrently with DetectFormat.
+func RegisterFormat(name string, offset int, magic []byte) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats = append(formats, signature{name, offset, bytes.Clone(magic)})
+}
+
+// DetectFormat identifies the data after the cursor by its magic bytes,
+// without consuming any of it. It returns the name of the format, such as
+// "gzip", "zip", "tar", "png", "jpeg", "pdf" or "sqlite", or "" if no
+// registered format matches.
+func (u *Unreader) DetectFormat() (string, error) {
+	// Sniff can block, so it mustn't hold up RegisterFormat. Registering only
+	// appends, leaving the signatures in this copy untouched.
+	formatsMu.RLock()
+	formats := formats
+	formatsMu.RUnlock()
+	n := 0
+	for _, s := range formats {
+		n = max(n, s.offset+len(s.magic))
+	}
+	b, err := u.Sniff(n)
+	if err != nil {
+		return "", err
+	}
+	for i := len(formats) - 1; i >= 0; i-- {
+		s := formats[i]
+		if len(b) >= s.offset+len(s.magic) && bytes.Equal(b[s.offset:s.offset+len(s.magic)], s.magic) {
+			return s.name, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,39 @@
+package unreader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tarHdr := make([]byte, 512)
+	copy(tarHdr[257:], "ustar\x0000")
+	for in, want := range map[string]string{
+		"\x1f\x8b\x08rest":      "gzip",
+		"PK\x03\x04":            "zip",
+		"\x89PNG\r\n\x1a\nIHDR": "png",
+		"%PDF-1.7\n":            "pdf",
+		string(tarHdr):          "tar",
+		"hello":                 "",
+		"":                      "",
+	} {
+		u, _ := NewUnreader(1024, strings.NewReader(in))
+		if f, err := u.DetectFormat(); f != want || err != nil || u.Cursor() != 0 {
+			t.Errorf("DetectFormat(%.10q) = %q, %v with cursor at %d, want %q", in, f, err, u.Cursor(), want)
+		}
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat("test-custom", 2, []byte("XY"))
+	if f, _ := NewUnreaderString("..XY").DetectFormat(); f != "test-custom" {
+		t.Errorf("DetectFormat() = %q, want test-custom", f)
+	}
+
+	// a DetectFormat waiting on a silent peer doesn't hold up registration
+	u := silentPeer(t, []byte("PK"))
+	go u.DetectFormat()
+	if !returns(func() { RegisterFormat("test-late", 0, []byte("LATE")) }) {
+		t.Fatal("RegisterFormat() blocked behind DetectFormat()")
+	}
+}
@@ -0,0 +1,140 @@
+package unreader
+
+import (
+	"encoding/binary"
+	"io"
+	"iter"
+	"math"
+)
+
+// ReadFrame reads a length-prefixed frame: a header of headerLen bytes,
+// from which lengthOf computes how many bytes of body follow it. It returns
+// a copy of the whole frame, header included, which can be pushed back with
+// UnreadToken. Errors from lengthOf are returned as they are.
+//
+// If the frame isn't complete, ReadFrame consumes nothing and returns an
+// *IncompleteError saying how many more bytes are needed, or io.EOF if the
+// stream ended cleanly before the frame. The whole frame must fit in the
+// buffer, and in the maximum token size, or ReadFrame returns ErrBufferFull
+// or ErrTokenTooLong without reading its body. A headerLen less than 1
+// returns ErrInvalidFrame.
+func (u *Unreader) ReadFrame(headerLen int, lengthOf func(header []byte) (int, error)) ([]byte, error) {
+	u.clearLast()
+	if headerLen < 1 {
+		return nil, ErrInvalidFrame
+	}
+	h, err := u.Peek(headerLen)
+	if len(h) < headerLen {
+		return nil, incomplete(headerLen, h, err)
+	}
+	n, err := lengthOf(h)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+	size := headerLen + n
+	if u.maxToken > 0 && size > u.maxToken {
+		return nil, ErrTokenTooLong
+	}
+	b, err := u.Peek(size)
+	if len(b) < size {
+		return nil, incomplete(size, b, err)
+	}
+	frame := append([]byte(nil), b...)
+	u.cursor += int64(size)
+	u.lastTokenSize = size
+	return frame, nil
+}
+
+// incomplete returns the error for a record of size bytes of which only b
+// could be peeked, because of err.
+func incomplete(size int, b []byte, err error) error {
+	switch {
+	case err == ErrBufferFull || err == ErrNegativeCount:
+		return err
+	case err == io.EOF && len(b) == 0:
+		return io.EOF
+	case err == io.EOF:
+		err = io.ErrUnexpectedEOF
+	}
+	return &IncompleteError{Need: size - len(b), Err: err}
+}
+
+// TLV describes the layout of type-length-value records for ReadTLV: a tag
+// of TagSize bytes, then a length of LengthSize bytes giving the size of
+// the value that follows. Both are unsigned integers of 1 to 8 bytes in the
+// given byte order, or big-endian if Order is nil.
+type TLV struct {
+	TagSize    int
+	LengthSize int
+	Order      binary.ByteOrder
+}
+
+// Record is a type-length-value record read by ReadTLV.
+type Record struct {
+	Tag   uint64
+	Value []byte
+}
+
+// ReadTLV reads a record laid out as f describes. It's read as a frame by
+// ReadFrame, with the same handling of incomplete records, and the whole
+// record can be pushed back with UnreadToken.
+func (u *Unreader) ReadTLV(f TLV) (Record, error) {
+	if f.TagSize < 1 || f.TagSize > 8 || f.LengthSize < 1 || f.LengthSize > 8 {
+		return Record{}, ErrInvalidTLV
+	}
+	if f.Order == nil {
+		f.Order = binary.BigEndian
+	}
+	b, err := u.ReadFrame(f.TagSize+f.LengthSize, func(h []byte) (int, error) {
+		n := uintN(h[f.TagSize:], f.Order)
+		if n > math.MaxInt32 {
+			return 0, ErrTokenTooLong
+		}
+		return int(n), nil
+	})
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{
+		Tag:   uintN(b[:f.TagSize], f.Order),
+		Value: b[f.TagSize+f.LengthSize:],
+	}, nil
+}
+
+// Records returns an iterator over the remaining records, as read by
+// ReadTLV. Iteration stops at the end of the stream; any other error,
+// including a record cut short, is yielded once with a zero Record.
+func (u *Unreader) Records(f TLV) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		for {
+			r, err := u.ReadTLV(f)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(Record{}, err)
+				return
+			}
+			if !yield(r, nil) {
+				return
+			}
+		}
+	}
+}
+
+// uintN decodes b, of up to 8 bytes, as an unsigned integer in order.
+func uintN(b []byte, order binary.ByteOrder) uint64 {
+	little := order.Uint16([]byte{1, 0}) == 1
+	var v uint64
+	for i := range b {
+		c := b[i]
+		if little {
+			c = b[len(b)-1-i]
+		}
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
@@ -0,0 +1,129 @@
+package unreader
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadFrame(t *testing.T) {
+	lenOf := func(h []byte) (int, error) { return int(binary.BigEndian.Uint16(h)), nil }
+	tests := []struct {
+		in   string
+		want string
+		need int
+		err  error
+	}{
+		{"\x00\x03abcd", "\x00\x03abc", 0, nil},
+		{"\x00\x00", "\x00\x00", 0, nil},
+		{"\x00\x03ab", "", 1, io.ErrUnexpectedEOF},
+		{"\x00", "", 1, io.ErrUnexpectedEOF},
+		{"", "", 0, io.EOF},
+		{"\x00\x20" + strings.Repeat("x", 32), "", 0, ErrBufferFull},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		f, err := u.ReadFrame(2, lenOf)
+		if string(f) != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ReadFrame(%q) = %q, %v, want %q, %v", tt.in, f, err, tt.want, tt.err)
+		}
+		var ie *IncompleteError
+		if errors.As(err, &ie) != (tt.need > 0) || tt.need > 0 && ie.Need != tt.need {
+			t.Errorf("ReadFrame(%q) error = %#v, want %d more bytes needed", tt.in, err, tt.need)
+		}
+		if err != nil && u.Cursor() != 0 {
+			t.Errorf("ReadFrame(%q) moved the cursor to %d on error", tt.in, u.Cursor())
+		}
+	}
+}
+
+func TestReadFrameStall(t *testing.T) {
+	lenOf := func(h []byte) (int, error) { return int(binary.BigEndian.Uint16(h)), nil }
+	u, _ := NewUnreader(16, &stallReader{parts: []string{"\x00\x03ab", "", "c\x00\x01z"}})
+	var ie *IncompleteError
+	if _, err := u.ReadFrame(2, lenOf); !errors.As(err, &ie) || ie.Need != 1 || u.Cursor() != 0 {
+		t.Fatalf("ReadFrame() error = %v at cursor %d, want 1 more byte needed at 0", err, u.Cursor())
+	}
+	if f, err := u.ReadFrame(2, lenOf); string(f) != "\x00\x03abc" || err != nil {
+		t.Fatalf("ReadFrame() = %q, %v", f, err)
+	}
+	if err := u.UnreadToken(); err != nil || u.Cursor() != 0 {
+		t.Fatalf("UnreadToken() = %v, cursor at %d", err, u.Cursor())
+	}
+}
+
+func TestReadTLV(t *testing.T) {
+	tests := []struct {
+		in   string
+		f    TLV
+		want Record
+		err  error
+	}{
+		{"\x07\x02\x00\x00hi", TLV{1, 3, binary.LittleEndian}, Record{7, []byte("hi")}, nil},
+		{"\x01\x02\x00\x01z", TLV{2, 2, binary.BigEndian}, Record{0x0102, []byte("z")}, nil},
+		{"\x08\x00", TLV{1, 1, binary.BigEndian}, Record{8, []byte{}}, nil},
+		{"\x09\x02z", TLV{1, 1, binary.BigEndian}, Record{}, io.ErrUnexpectedEOF},
+		{"", TLV{1, 1, binary.BigEndian}, Record{}, io.EOF},
+		{"\x01\x01", TLV{9, 1, binary.BigEndian}, Record{}, ErrInvalidTLV},
+		{"\x01\x01", TLV{1, 0, binary.BigEndian}, Record{}, ErrInvalidTLV},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		r, err := u.ReadTLV(tt.f)
+		if r.Tag != tt.want.Tag || string(r.Value) != string(tt.want.Value) || !errors.Is(err, tt.err) {
+			t.Errorf("ReadTLV(%q) = %v, %v, want %v, %v", tt.in, r, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	f := TLV{TagSize: 1, LengthSize: 1, Order: binary.BigEndian}
+	u, _ := NewUnreader(16, strings.NewReader("\x01\x01a\x02\x00\x03\x02b"))
+	var tags []uint64
+	var last error
+	for r, err := range u.Records(f) {
+		if err != nil {
+			last = err
+			break
+		}
+		tags = append(tags, r.Tag)
+	}
+	var ie *IncompleteError
+	if len(tags) != 2 || tags[0] != 1 || tags[1] != 2 || !errors.As(last, &ie) || ie.Need != 1 {
+		t.Errorf("Records() yielded tags %v and error %v", tags, last)
+	}
+	u.Reset(strings.NewReader("\x01\x00"))
+	n := 0
+	for _, err := range u.Records(f) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("Records() yielded %d records, want 1", n)
+	}
+}
+
+func TestReadFrameInvalidHeader(t *testing.T) {
+	u := NewUnreaderString("\x00\x01x")
+	for _, n := range []int{0, -1} {
+		_, err := u.ReadFrame(n, func(h []byte) (int, error) {
+			t.Fatalf("lengthOf called with header %q", h)
+			return 0, nil
+		})
+		if err != ErrInvalidFrame {
+			t.Errorf("ReadFrame(%d) error = %v, want ErrInvalidFrame", n, err)
+		}
+	}
+}
+
+func TestReadTLVDefaultOrder(t *testing.T) {
+	u := NewUnreaderString("\x01\x02\x00\x01z")
+	r, err := u.ReadTLV(TLV{TagSize: 2, LengthSize: 2})
+	if err != nil || r.Tag != 0x0102 || string(r.Value) != "z" {
+		t.Errorf("ReadTLV() with no byte order = %v, %v, want big-endian", r, err)
+	}
+}
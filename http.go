@@ -0,0 +1,283 @@
+package unreader
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// RequestLine is the first line of an HTTP/1.x request.
+type RequestLine struct {
+	Method  string // such as "GET"
+	Target  string // such as "/index.html" or "example.com:443"
+	Version string // "HTTP/1.0" or "HTTP/1.1"
+}
+
+// maxRequestLine bounds the request line PeekRequestLine looks for.
+const maxRequestLine = 8 << 10
+
+// PeekRequestLine parses the line at the cursor as an HTTP/1.x request
+// line without consuming it, so the connection can be handed, bytes and
+// all, to net/http or elsewhere. The line is checked as it arrives, so a
+// stream that isn't HTTP returns ErrNoMatch at its first byte that can't be
+// part of a request line, instead of waiting for a line the peer will never
+// send. A line longer than 8KiB returns ErrTokenTooLong, and a stream that
+// ends before or within the line returns io.EOF or io.ErrUnexpectedEOF.
+func (u *Unreader) PeekRequestLine() (RequestLine, error) {
+	n, err := u.peekRequest(maxRequestLine, false)
+	if err != nil {
+		return RequestLine{}, err
+	}
+	return parseRequestLine(trimEOL(u.replay()[:n]))
+}
+
+// peekRequest returns the length of the HTTP/1.x request line at the
+// cursor, or with header, of the whole request head through the blank line
+// ending it, checking the bytes as they arrive. It reads at most max bytes,
+// or up to the token limit if max is 0 or less.
+func (u *Unreader) peekRequest(max int, header bool) (int, error) {
+	match := true
+	n, err := u.peekScan(max, func(b []byte, atEOF bool) int {
+		n, ok := scanRequestLine(b)
+		if ok && n > 0 && header {
+			n, ok = scanHeaderFields(b, n)
+		}
+		if !ok {
+			match = false
+			return 0
+		}
+		return n
+	})
+	if !match {
+		return 0, ErrNoMatch
+	}
+	return n, err
+}
+
+// scanRequestLine returns the length of the request line at the start of
+// b, including its end-of-line bytes, or -1 if b ends within it. ok is
+// false if b can't start a request line.
+func scanRequestLine(b []byte) (n int, ok bool) {
+	i := 0
+	for ; i < len(b) && b[i] != ' '; i++ {
+		if !isTokenChar(b[i]) {
+			return 0, false
+		}
+	}
+	if i == len(b) {
+		return -1, true
+	}
+	if i == 0 {
+		return 0, false
+	}
+	i++
+	start := i
+	for ; i < len(b) && b[i] != ' '; i++ {
+		if b[i] < ' ' || b[i] == 0x7f {
+			return 0, false
+		}
+	}
+	if i == len(b) {
+		return -1, true
+	}
+	if i == start {
+		return 0, false
+	}
+	i++
+	const version = "HTTP/1."
+	for j := 0; j <= len(version); i, j = i+1, j+1 {
+		if i == len(b) {
+			return -1, true
+		}
+		if j == len(version) && (b[i] < '0' || b[i] > '9') || j < len(version) && b[i] != version[j] {
+			return 0, false
+		}
+	}
+	return lineEnd(b, i)
+}
+
+// scanHeaderFields returns the length of b through the header fields at
+// b[i:] and the blank line ending them, or -1 if b ends within them. ok is
+// false if the bytes can't be header fields.
+func scanHeaderFields(b []byte, i int) (n int, ok bool) {
+	for {
+		if i == len(b) {
+			return -1, true
+		}
+		if b[i] == '\r' || b[i] == '\n' {
+			return lineEnd(b, i)
+		}
+		start := i
+		for ; i < len(b) && b[i] != ':'; i++ {
+			if !isTokenChar(b[i]) {
+				return 0, false
+			}
+		}
+		if i == len(b) {
+			return -1, true
+		}
+		if i == start {
+			return 0, false
+		}
+		for ; i < len(b) && b[i] != '\r' && b[i] != '\n'; i++ {
+			if b[i] < ' ' && b[i] != '\t' || b[i] == 0x7f {
+				return 0, false
+			}
+		}
+		if n, ok = lineEnd(b, i); n < 0 || !ok {
+			return n, ok
+		}
+		i = n
+	}
+}
+
+// lineEnd returns the length of b through the "\r\n" or "\n" at b[i:], or
+// -1 if b ends first. ok is false if something else is there.
+func lineEnd(b []byte, i int) (n int, ok bool) {
+	if i < len(b) && b[i] == '\r' {
+		i++
+	}
+	if i == len(b) {
+		return -1, true
+	}
+	if b[i] != '\n' {
+		return 0, false
+	}
+	return i + 1, true
+}
+
+// trimEOL removes the "\n" or "\r\n" ending line.
+func trimEOL(line []byte) []byte {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r"))
+}
+
+// parseRequestLine parses line, without its end-of-line bytes, as an
+// HTTP/1.x request line.
+func parseRequestLine(line []byte) (RequestLine, error) {
+	method, rest, ok1 := bytes.Cut(line, []byte(" "))
+	target, version, ok2 := bytes.Cut(rest, []byte(" "))
+	if !ok1 || !ok2 || !isToken(method) || len(target) == 0 || !validTarget(target) || !isHTTP1(version) {
+		return RequestLine{}, ErrNoMatch
+	}
+	return RequestLine{string(method), string(target), string(version)}, nil
+}
+
+// PeekRequestHeader parses the HTTP/1.x request line and header fields at
+// the cursor, through the blank line ending them, without consuming them.
+// Like the request line, the header fields are checked as they arrive. The
+// header block must be within max bytes, or the buffer if max is 0 or
+// less; if it isn't, PeekRequestHeader returns ErrTokenTooLong. It returns
+// ErrNoMatch if the block isn't a valid request header, and io.EOF or
+// io.ErrUnexpectedEOF if the stream ends before or within it.
+func (u *Unreader) PeekRequestHeader(max int) (RequestLine, http.Header, error) {
+	n, err := u.peekRequest(max, true)
+	if err != nil {
+		return RequestLine{}, nil, err
+	}
+	head := u.replay()[:n]
+	i := bytes.IndexByte(head, '\n')
+	rl, err := parseRequestLine(trimEOL(head[:i+1]))
+	if err != nil {
+		return RequestLine{}, nil, err
+	}
+	h, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(head[i+1:]))).ReadMIMEHeader()
+	if err != nil {
+		return RequestLine{}, nil, ErrNoMatch
+	}
+	return rl, http.Header(h), nil
+}
+
+// IsWebSocketUpgrade reports whether the request header at the cursor, read
+// as by PeekRequestHeader, asks to upgrade to a WebSocket (RFC 6455 4.1).
+// Nothing is consumed.
+func (u *Unreader) IsWebSocketUpgrade(max int) bool {
+	rl, h, err := u.PeekRequestHeader(max)
+	return err == nil && rl.Method == "GET" &&
+		hasToken(h.Values("Connection"), "upgrade") &&
+		hasToken(h.Values("Upgrade"), "websocket") &&
+		h.Get("Sec-WebSocket-Key") != ""
+}
+
+// MatchWebSocket returns a Matcher for WebSocket upgrade requests, as
+// reported by IsWebSocketUpgrade with the given bound on the header size.
+func MatchWebSocket(max int) Matcher {
+	return MatcherFunc(func(u *Unreader) bool {
+		return u.IsWebSocketUpgrade(max)
+	})
+}
+
+// hasToken reports whether any of the comma-separated lists in values
+// holds token, compared without regard to case.
+func hasToken(values []string, token string) bool {
+	for _, v := range values {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchHTTP1 returns a Matcher for streams starting with an HTTP/1.x
+// request line.
+func MatchHTTP1() Matcher {
+	return MatcherFunc(func(u *Unreader) bool {
+		_, err := u.PeekRequestLine()
+		return err == nil
+	})
+}
+
+// isToken reports whether b is a non-empty HTTP token (RFC 9110 5.6.2).
+func isToken(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if !isTokenChar(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTokenChar reports whether c can be part of an HTTP token.
+func isTokenChar(c byte) bool {
+	return c > ' ' && c < 0x7f && strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) < 0
+}
+
+// validTarget reports whether b has no spaces or control bytes.
+func validTarget(b []byte) bool {
+	for _, c := range b {
+		if c <= ' ' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// isHTTP1 reports whether b is "HTTP/1." followed by a digit.
+func isHTTP1(b []byte) bool {
+	return len(b) == 8 && bytes.HasPrefix(b, []byte("HTTP/1.")) && '0' <= b[7] && b[7] <= '9'
+}
+
+// http2Preface is the connection preface an HTTP/2 client sends first
+// (RFC 9113 3.4).
+const http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// IsHTTP2Preface reports whether the stream starts with the HTTP/2 client
+// connection preface, as sent by h2c clients using prior knowledge. It
+// gives up at the first byte that differs from the preface, so an HTTP/1
+// client waiting for a reply isn't waited on, and consumes nothing.
+func (u *Unreader) IsHTTP2Preface() bool {
+	return u.HasPrefix([]byte(http2Preface))
+}
+
+// MatchHTTP2 returns a Matcher for streams starting with the HTTP/2 client
+// connection preface.
+func MatchHTTP2() Matcher {
+	return MatcherFunc((*Unreader).IsHTTP2Preface)
+}
@@ -0,0 +1,105 @@
+package unreader
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPeekRequestLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RequestLine
+		err  error
+	}{
+		{"GET /index.html HTTP/1.1\r\nHost: x\r\n", RequestLine{"GET", "/index.html", "HTTP/1.1"}, nil},
+		{"CONNECT example.com:443 HTTP/1.0\n", RequestLine{"CONNECT", "example.com:443", "HTTP/1.0"}, nil},
+		{"M-SEARCH * HTTP/1.1\r\n", RequestLine{"M-SEARCH", "*", "HTTP/1.1"}, nil},
+		{"GET /index.html HTTP/2.0\r\n", RequestLine{}, ErrNoMatch},
+		{"GET  / HTTP/1.1\r\n", RequestLine{}, ErrNoMatch},
+		{"GET / HTTP/1.1 \r\n", RequestLine{}, ErrNoMatch},
+		{"GET / HTTP/1.1\rX", RequestLine{}, ErrNoMatch},
+		{"G(T / HTTP/1.1\r\n", RequestLine{}, ErrNoMatch},
+		{"SSH-2.0-OpenSSH_9.6\r\n", RequestLine{}, ErrNoMatch},
+		{"\x16\x03\x01\x00", RequestLine{}, ErrNoMatch},
+		{"GET / HTTP/1.1", RequestLine{}, io.ErrUnexpectedEOF},
+		{"", RequestLine{}, io.EOF},
+		{"GET /" + strings.Repeat("a", maxRequestLine) + " HTTP/1.1\r\n", RequestLine{}, ErrTokenTooLong},
+	}
+	for _, tt := range tests {
+		u, _ := New(strings.NewReader(tt.in), WithGrowable(true))
+		got, err := u.PeekRequestLine()
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("PeekRequestLine(%.40q) = %+v, %v; want %+v, %v", tt.in, got, err, tt.want, tt.err)
+		}
+		if u.Cursor() != 0 {
+			t.Errorf("PeekRequestLine(%.40q) consumed %d bytes", tt.in, u.Cursor())
+		}
+	}
+}
+
+func TestPeekRequestHeader(t *testing.T) {
+	const upgrade = "GET /chat HTTP/1.1\r\nHost: x\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Upgrade: websocket\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\nbody"
+	tests := []struct {
+		in        string
+		max       int
+		host      string
+		websocket bool
+		err       error
+	}{
+		{upgrade, 0, "x", true, nil},
+		{"GET / HTTP/1.1\r\nHost: y\r\n\r\n", 0, "y", false, nil},
+		{"GET / HTTP/1.1\nHost: y\n\n", 0, "y", false, nil},
+		{"POST /chat HTTP/1.1\r\nConnection: upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Key: k\r\n\r\n", 0, "", false, nil},
+		{"GET / HTTP/1.1\r\nBad Name: y\r\n\r\n", 0, "", false, ErrNoMatch},
+		{"GET / HTTP/1.1\r\nHost: y\x00\r\n\r\n", 0, "", false, ErrNoMatch},
+		{"GET / HTTP/1.1\r\nHost: y\r\n", 0, "", false, io.ErrUnexpectedEOF},
+		{upgrade, 40, "", false, ErrTokenTooLong},
+		{"\x05\x01\x00", 0, "", false, ErrNoMatch},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(512, strings.NewReader(tt.in))
+		_, h, err := u.PeekRequestHeader(tt.max)
+		if !errors.Is(err, tt.err) || err == nil && h.Get("Host") != tt.host {
+			t.Errorf("PeekRequestHeader(%.30q, %d) = %v, %v; want Host %q, %v", tt.in, tt.max, h, err, tt.host, tt.err)
+		}
+		if ws := u.IsWebSocketUpgrade(tt.max); ws != tt.websocket {
+			t.Errorf("IsWebSocketUpgrade(%.30q, %d) = %v", tt.in, tt.max, ws)
+		}
+		if u.Cursor() != 0 {
+			t.Errorf("PeekRequestHeader(%.30q) consumed %d bytes", tt.in, u.Cursor())
+		}
+	}
+}
+
+func TestIsHTTP2Preface(t *testing.T) {
+	for in, want := range map[string]bool{
+		http2Preface + "\x00\x00":  true,
+		http2Preface[:10]:          false,
+		"PRI * HTTP/1.1\r\n\r\n":   false,
+		"GET / HTTP/1.1\r\n\r\nxx": false,
+		"":                         false,
+	} {
+		u := NewUnreaderString(in)
+		if got := u.IsHTTP2Preface(); got != want || u.Cursor() != 0 {
+			t.Errorf("IsHTTP2Preface(%q) = %v with cursor at %d, want %v", in, got, u.Cursor(), want)
+		}
+	}
+	u, _ := NewUnreader(64, strings.NewReader(http2Preface))
+	if i := u.Detect(MatchHTTP1(), MatchHTTP2()); i != 1 {
+		t.Errorf("Detect() = %d, want 1 for MatchHTTP2", i)
+	}
+}
+
+func TestIsHTTP2PrefaceSilentPeer(t *testing.T) {
+	u := silentPeer(t, []byte("GET / HTTP/1.0\r\n\r\n"))
+	i := -2
+	if !returns(func() { i = u.Detect(MatchHTTP2(), MatchHTTP1()) }) {
+		t.Fatal("Detect() blocked on an HTTP/1.0 request shorter than the preface")
+	}
+	if i != 1 {
+		t.Errorf("Detect() = %d, want 1 for MatchHTTP1", i)
+	}
+}
@@ -0,0 +1,77 @@
+package unreader
+
+import (
+	"io"
+	"iter"
+)
+
+// Lines returns an iterator over the remaining lines, as read by ReadLine.
+// Iteration stops at the end of the stream; any other error is yielded once
+// with whatever of the line was read before it. After breaking out of the
+// loop, UnreadLine pushes back the last line yielded.
+func (u *Unreader) Lines() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for {
+			line, err := u.ReadLine()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(line, err)
+				return
+			}
+			if !yield(line, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Runes returns an iterator over the remaining runes, as read by ReadRune.
+// Iteration stops at the end of the stream; any other error is yielded once
+// with a zero rune. The loop body may call UnreadRune to push the current
+// rune back, in which case the next iteration yields it again.
+func (u *Unreader) Runes() iter.Seq2[rune, error] {
+	return func(yield func(rune, error) bool) {
+		for {
+			r, _, err := u.ReadRune()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(0, err)
+				return
+			}
+			if !yield(r, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Chunks returns an iterator over the remaining bytes in blocks of n, with
+// the last block short if the stream doesn't end on a boundary. Each block
+// is a new slice. Previous blocks can be unread to as long as they are still
+// held in the buffer.
+func (u *Unreader) Chunks(n int) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if n <= 0 {
+			yield(nil, ErrNegativeCount)
+			return
+		}
+		for {
+			b := make([]byte, n)
+			m, err := u.ReadFull(b)
+			if m > 0 && !yield(b[:m], nil) {
+				return
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package unreader
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLines(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name  string
+		in    io.Reader
+		lines []string // including any yielded with err
+		err   error
+	}{
+		{"lines", strings.NewReader("a\r\nb\nc"), []string{"a", "b", "c"}, nil},
+		{"empty", strings.NewReader(""), nil, nil},
+		{"error", io.MultiReader(strings.NewReader("a\nb"), iotest.ErrReader(errBoom)), []string{"a", "b"}, errBoom},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, tt.in)
+		var lines []string
+		var err error
+		for line, lerr := range u.Lines() {
+			if lerr != nil {
+				err = lerr
+			}
+			lines = append(lines, string(line))
+		}
+		if !slices.Equal(lines, tt.lines) || err != tt.err {
+			t.Errorf("%s: Lines() = %q, %v, want %q, %v", tt.name, lines, err, tt.lines, tt.err)
+		}
+	}
+
+	// the last line yielded can be pushed back after breaking out
+	u := NewUnreaderString("skip\nkeep\nrest")
+	for line := range u.Lines() {
+		if string(line) == "keep" {
+			break
+		}
+	}
+	if err := u.UnreadLine(); err != nil {
+		t.Fatalf("UnreadLine() after break = %v", err)
+	}
+	if rest, _ := io.ReadAll(u); string(rest) != "keep\nrest" {
+		t.Fatalf("left %q", rest)
+	}
+}
+
+func TestRunes(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name  string
+		in    io.Reader
+		runes string
+		err   error
+	}{
+		{"runes", strings.NewReader("aé€"), "aé€", nil},
+		{"split across reads", iotest.OneByteReader(strings.NewReader("é€")), "é€", nil},
+		{"empty", strings.NewReader(""), "", nil},
+		{"error", io.MultiReader(strings.NewReader("ab"), iotest.ErrReader(errBoom)), "ab", errBoom},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, tt.in)
+		var runes []rune
+		var err error
+		for r, rerr := range u.Runes() {
+			if rerr != nil {
+				err = rerr
+				continue
+			}
+			runes = append(runes, r)
+		}
+		if string(runes) != tt.runes || err != tt.err {
+			t.Errorf("%s: Runes() = %q, %v, want %q, %v", tt.name, string(runes), err, tt.runes, tt.err)
+		}
+	}
+
+	// UnreadRune in the loop body yields the rune again
+	u := NewUnreaderString("ab")
+	var got []rune
+	for r := range u.Runes() {
+		got = append(got, r)
+		if len(got) == 2 {
+			u.UnreadRune()
+		}
+	}
+	if string(got) != "abb" {
+		t.Fatalf("Runes() with pushback = %q, want %q", string(got), "abb")
+	}
+}
+
+func TestChunks(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     io.Reader
+		n      int
+		chunks []string
+		err    error
+	}{
+		{"even", strings.NewReader("012345"), 3, []string{"012", "345"}, nil},
+		{"short last", strings.NewReader("01234"), 3, []string{"012", "34"}, nil},
+		{"one byte reads", iotest.OneByteReader(strings.NewReader("01234")), 2, []string{"01", "23", "4"}, nil},
+		{"empty", strings.NewReader(""), 3, nil, nil},
+		{"zero size", strings.NewReader("01"), 0, nil, ErrNegativeCount},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(8, tt.in)
+		var chunks []string
+		var err error
+		for b, cerr := range u.Chunks(tt.n) {
+			if cerr != nil {
+				err = cerr
+				continue
+			}
+			chunks = append(chunks, string(b))
+		}
+		if !slices.Equal(chunks, tt.chunks) || err != tt.err {
+			t.Errorf("%s: Chunks(%d) = %q, %v, want %q, %v", tt.name, tt.n, chunks, err, tt.chunks, tt.err)
+		}
+	}
+
+	// earlier chunks can be unread to while still buffered
+	u, _ := NewUnreader(8, strings.NewReader("0123456789"))
+	for b := range u.Chunks(4) {
+		if string(b) == "4567" {
+			break
+		}
+	}
+	if err := u.Unread(8); err != nil {
+		t.Fatalf("Unread(8) into the previous chunk = %v", err)
+	}
+	if rest, _ := io.ReadAll(u); string(rest) != "0123456789" {
+		t.Fatalf("left %q", rest)
+	}
+}
@@ -0,0 +1,73 @@
+package unreader
+
+import (
+	"io"
+	"strings"
+)
+
+// EOFRune is returned by Next when there is no more input.
+const EOFRune rune = -1
+
+// Next reads and returns the next rune for a lexer, or EOFRune if the stream
+// has ended. Errors other than io.EOF also return EOFRune, and are kept to be
+// returned by the next read.
+func (u *Unreader) Next() rune {
+	r, _, err := u.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			u.err = err
+		}
+		return EOFRune
+	}
+	return r
+}
+
+// Backup unreads the rune returned by the last Next. It can be called only
+// once per call of Next.
+func (u *Unreader) Backup() {
+	u.UnreadRune()
+}
+
+// Accept consumes the next rune if it's in set, reporting whether it did.
+func (u *Unreader) Accept(set string) bool {
+	r, size, err := u.PeekRune()
+	if err != nil || !strings.ContainsRune(set, r) {
+		return false
+	}
+	u.clearLast()
+	u.cursor += int64(size)
+	u.lastRuneSize = size
+	return true
+}
+
+// AcceptRun consumes a run of runes from set, returning how many bytes it
+// consumed.
+func (u *Unreader) AcceptRun(set string) int {
+	n, _ := u.runesWhile(func(r rune) bool { return strings.ContainsRune(set, r) }, nil)
+	return n
+}
+
+// Pending returns the bytes consumed since the token started, at the last
+// Emit or Ignore, without ending it. It returns ErrEvicted if the token is
+// longer than the buffer holds.
+func (u *Unreader) Pending() ([]byte, error) {
+	start := min(u.lexStart, u.cursor)
+	if start < u.bytesRead-u.retained() {
+		return nil, ErrEvicted
+	}
+	b := u.bytes()
+	base := u.bytesRead - int64(len(b))
+	return append([]byte(nil), b[start-base:u.cursor-base]...), nil
+}
+
+// Emit returns the pending token and starts a new one at the cursor.
+func (u *Unreader) Emit() ([]byte, error) {
+	b, err := u.Pending()
+	u.lexStart = u.cursor
+	return b, err
+}
+
+// Ignore drops the pending token and starts a new one at the cursor.
+func (u *Unreader) Ignore() {
+	u.lexStart = u.cursor
+}
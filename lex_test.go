@@ -0,0 +1,57 @@
+package unreader
+
+import "testing"
+
+func TestLexer(t *testing.T) {
+	u := NewUnreaderString("x = 42;")
+	var toks []string
+	emit := func() {
+		b, err := u.Emit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		toks = append(toks, string(b))
+	}
+	for {
+		switch r := u.Next(); {
+		case r == EOFRune:
+			want := []string{"x", "=", "42", ";"}
+			if len(toks) != len(want) {
+				t.Fatalf("tokens = %q, want %q", toks, want)
+			}
+			for i := range want {
+				if toks[i] != want[i] {
+					t.Fatalf("tokens = %q, want %q", toks, want)
+				}
+			}
+			return
+		case r == ' ':
+			u.AcceptRun(" ")
+			u.Ignore()
+		case '0' <= r && r <= '9':
+			u.Backup()
+			if n := u.AcceptRun("0123456789"); n != 2 {
+				t.Fatalf("AcceptRun() = %d, want 2", n)
+			}
+			if p, _ := u.Pending(); string(p) != "42" {
+				t.Fatalf("Pending() = %q, want 42", p)
+			}
+			emit()
+		default:
+			emit()
+		}
+	}
+}
+
+func TestLexerAccept(t *testing.T) {
+	u := NewUnreaderString("ab")
+	if u.Accept("xyz") {
+		t.Fatal("Accept(xyz) = true")
+	}
+	if !u.Accept("ba") || !u.Accept("b") || u.Accept("ab") {
+		t.Fatal("Accept() didn't consume a and b")
+	}
+	if r := u.Next(); r != EOFRune {
+		t.Fatalf("Next() at end = %q, want EOFRune", r)
+	}
+}
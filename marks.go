@@ -0,0 +1,69 @@
+package unreader
+
+// Mark is a checkpoint of the cursor position, returned by Unreader.Mark.
+type Mark struct {
+	off int64
+}
+
+// Offset returns the absolute stream offset of the mark.
+func (m Mark) Offset() int64 {
+	return m.off
+}
+
+// Mark returns a checkpoint of the current cursor position that can be
+// passed to Rewind.
+func (u *Unreader) Mark() Mark {
+	return Mark{off: u.cursor}
+}
+
+// Rewind restores the cursor to m. It fails with an *UnreadError if the
+// bytes after m have been evicted from the buffer.
+func (u *Unreader) Rewind(m Mark) error {
+	if m.off > u.cursor {
+		return u.Advance(m.off - u.cursor)
+	}
+	return u.Unread(u.cursor - m.off)
+}
+
+type namedMark struct {
+	name string
+	Mark
+}
+
+// PushMark pushes a mark at the cursor onto the Unreader's mark stack under
+// name and returns it. Names need not be unique. While a mark is on the
+// stack, the bytes after it are pinned in the buffer: reads that would evict
+// them grow the buffer if it is growable, or fail with ErrPinned.
+func (u *Unreader) PushMark(name string) Mark {
+	m := u.Mark()
+	u.marks = append(u.marks, namedMark{name: name, Mark: m})
+	return m
+}
+
+// PopMark removes the most recently pushed mark and returns it, without
+// moving the cursor. It returns ErrNoMark if the stack is empty.
+func (u *Unreader) PopMark() (Mark, error) {
+	if len(u.marks) == 0 {
+		return Mark{}, ErrNoMark
+	}
+	m := u.marks[len(u.marks)-1]
+	u.marks = u.marks[:len(u.marks)-1]
+	return m.Mark, nil
+}
+
+// RewindTo rewinds the cursor to the most recently pushed mark named name.
+// Marks pushed after it are dropped, and it stays on the stack so it can be
+// rewound to again. It returns ErrNoMark if no mark has that name.
+func (u *Unreader) RewindTo(name string) error {
+	for i := len(u.marks) - 1; i >= 0; i-- {
+		if u.marks[i].name != name {
+			continue
+		}
+		if err := u.Rewind(u.marks[i].Mark); err != nil {
+			return err
+		}
+		u.marks = u.marks[:i+1]
+		return nil
+	}
+	return ErrNoMark
+}
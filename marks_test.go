@@ -0,0 +1,160 @@
+package unreader
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMarks(t *testing.T) {
+	u := NewUnreaderString("abcdef")
+	u.PushMark("outer")
+	u.Discard(2)
+	u.PushMark("inner")
+	u.Discard(2)
+	if err := u.RewindTo("outer"); err != nil || u.Cursor() != 0 {
+		t.Fatalf("RewindTo(outer) = %v, cursor at %d", err, u.Cursor())
+	}
+	if _, err := u.PopMark(); err != nil {
+		t.Fatalf("PopMark() = %v", err)
+	}
+	if _, err := u.PopMark(); err != ErrNoMark {
+		t.Fatalf("PopMark() on empty stack = %v, want ErrNoMark", err)
+	}
+	m := u.Mark()
+	u.Discard(3)
+	if err := u.Rewind(m); err != nil || u.Cursor() != 0 {
+		t.Fatalf("Rewind() = %v, cursor at %d", err, u.Cursor())
+	}
+}
+
+// TestPinned checks that bytes held by a mark or transaction are never
+// evicted from a buffer that can't grow, and are released once it's gone.
+func TestPinned(t *testing.T) {
+	tests := []struct {
+		name    string
+		pin     func(u *Unreader)
+		release func(u *Unreader)
+	}{
+		{"mark", func(u *Unreader) { u.PushMark("m") }, func(u *Unreader) { u.RewindTo("m"); u.PopMark() }},
+		{"transaction", func(u *Unreader) { u.Begin() }, func(u *Unreader) { u.Rollback() }},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+		u.Discard(1)
+		tt.pin(u)
+		p := make([]byte, 3)
+		if _, err := io.ReadFull(u, p); err != nil {
+			t.Fatalf("%s: ReadFull() = %v", tt.name, err)
+		}
+		// one more byte fits by evicting the unpinned first byte
+		if n, err := u.Read(p); n != 1 || err != nil {
+			t.Fatalf("%s: Read() = %d, %v, want 1 byte", tt.name, n, err)
+		}
+		if _, err := u.Read(p); err != ErrPinned {
+			t.Fatalf("%s: Read() past the buffer = %v, want ErrPinned", tt.name, err)
+		}
+		if _, err := u.Peek(2); err != ErrPinned {
+			t.Fatalf("%s: Peek() past the buffer = %v, want ErrPinned", tt.name, err)
+		}
+		if u.Cursor() != 5 {
+			t.Fatalf("%s: cursor at %d after ErrPinned, want 5", tt.name, u.Cursor())
+		}
+		tt.release(u)
+		if u.Cursor() != 1 {
+			t.Fatalf("%s: cursor at %d after release, want 1", tt.name, u.Cursor())
+		}
+		if b, err := io.ReadAll(u); string(b) != "123456789" || err != nil {
+			t.Errorf("%s: ReadAll() after release = %q, %v", tt.name, b, err)
+		}
+	}
+}
+
+func TestPinnedGrowable(t *testing.T) {
+	u, _ := New(strings.NewReader("0123456789"), WithBufferSize(2), WithGrowable(true))
+	u.Begin()
+	b, _ := io.ReadAll(u)
+	if err := u.Rollback(); err != nil || len(b) != 10 || u.Cursor() != 0 {
+		t.Fatalf("Rollback() = %v after reading %q, cursor at %d", err, b, u.Cursor())
+	}
+	if b, _ := io.ReadAll(u); string(b) != "0123456789" {
+		t.Errorf("ReadAll() after rollback = %q", b)
+	}
+}
+
+func TestRewind(t *testing.T) {
+	tests := []struct {
+		name   string
+		mark   int64 // bytes read before the mark
+		after  int64 // bytes read after it
+		back   int64 // bytes unread before rewinding
+		err    error
+		cursor int64
+	}{
+		{"back", 2, 3, 0, nil, 2},
+		{"same place", 2, 0, 0, nil, 2},
+		{"forward into replay", 5, 0, 3, nil, 5},
+		{"evicted", 1, 6, 0, ErrUnreadBeyondBuffer, 7},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+		u.Discard(tt.mark)
+		m := u.Mark()
+		if m.Offset() != tt.mark {
+			t.Errorf("%s: Mark().Offset() = %d, want %d", tt.name, m.Offset(), tt.mark)
+		}
+		u.Discard(tt.after)
+		u.Unread(tt.back)
+		if err := u.Rewind(m); !errors.Is(err, tt.err) || u.Cursor() != tt.cursor {
+			t.Errorf("%s: Rewind() = %v, cursor %d, want %v, %d", tt.name, err, u.Cursor(), tt.err, tt.cursor)
+		}
+	}
+}
+
+func TestNestedMarks(t *testing.T) {
+	tests := []struct {
+		name   string
+		ops    func(u *Unreader) error
+		err    error
+		cursor int64
+		marks  int // left on the stack
+	}{
+		{"rewind to inner", func(u *Unreader) error { return u.RewindTo("b") }, nil, 4, 2},
+		{"rewind to outer drops inner", func(u *Unreader) error { return u.RewindTo("a") }, nil, 2, 1},
+		{"duplicate name finds latest", func(u *Unreader) error { return u.RewindTo("c") }, nil, 8, 4},
+		{"rewind twice", func(u *Unreader) error {
+			u.RewindTo("b")
+			u.Discard(1)
+			return u.RewindTo("b")
+		}, nil, 4, 2},
+		{"unknown", func(u *Unreader) error { return u.RewindTo("x") }, ErrNoMark, 10, 4},
+		{"pop", func(u *Unreader) error {
+			m, err := u.PopMark()
+			if m.Offset() != 8 {
+				t.Errorf("PopMark() = offset %d, want 8", m.Offset())
+			}
+			return err
+		}, nil, 10, 3},
+	}
+	for _, tt := range tests {
+		u := NewUnreaderString("0123456789")
+		for i, name := range []string{"a", "b", "c", "c"} {
+			u.Discard(2)
+			if m := u.PushMark(name); m.Offset() != int64(2*(i+1)) {
+				t.Fatalf("%s: PushMark(%q) at %d", tt.name, name, m.Offset())
+			}
+		}
+		u.Discard(2)
+		if err := tt.ops(u); err != tt.err || u.Cursor() != tt.cursor {
+			t.Errorf("%s: err %v, cursor %d, want %v, %d", tt.name, err, u.Cursor(), tt.err, tt.cursor)
+		}
+		n := 0
+		for _, err := u.PopMark(); err == nil; _, err = u.PopMark() {
+			n++
+		}
+		if n != tt.marks {
+			t.Errorf("%s: %d marks left, want %d", tt.name, n, tt.marks)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package unreader
+
+// Matcher recognizes a protocol or format from the upcoming bytes. Match
+// may read as much as it likes; when it's run by Detect, the cursor is
+// rewound afterwards whatever it reports.
+type Matcher interface {
+	Match(u *Unreader) bool
+}
+
+// MatcherFunc adapts a function to a Matcher.
+type MatcherFunc func(u *Unreader) bool
+
+// Match calls f(u).
+func (f MatcherFunc) Match(u *Unreader) bool {
+	return f(u)
+}
+
+// Detect runs the matchers in order and returns the index of the first that
+// matches, or -1 if none do. Each runs in a speculative region that is
+// rolled back when it returns, so the cursor is where it was before Detect
+// for every matcher and afterwards, and all the bytes they looked at can
+// still be read. A matcher reading more than the buffer can hold gets
+// ErrPinned rather than evicting them, unless the buffer is growable.
+func (u *Unreader) Detect(matchers ...Matcher) int {
+	for i, m := range matchers {
+		u.Begin()
+		ok := m.Match(u)
+		u.Rollback()
+		if ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchPrefix returns a Matcher for streams starting with any of prefixes.
+func MatchPrefix(prefixes ...string) Matcher {
+	return MatcherFunc(func(u *Unreader) bool {
+		for _, p := range prefixes {
+			if u.HasPrefix([]byte(p)) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// IsSSH reports whether the stream starts with an SSH-2.0 identification
+// string (RFC 4253 4.2), or the SSH-1.99 one servers compatible with both
+// versions send, without consuming anything. It waits for no more bytes
+// than it takes to tell.
+func (u *Unreader) IsSSH() bool {
+	return u.HasPrefix([]byte("SSH-")) &&
+		(u.HasPrefix([]byte("SSH-2.0-")) || u.HasPrefix([]byte("SSH-1.99-")))
+}
+
+// MatchSSH returns a Matcher for streams starting with an SSH
+// identification string, as reported by IsSSH.
+func MatchSSH() Matcher {
+	return MatcherFunc((*Unreader).IsSSH)
+}
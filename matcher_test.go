@@ -0,0 +1,83 @@
+package unreader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	matchers := []Matcher{
+		MatchPrefix("GET ", "POST "),
+		MatchPrefix("\x16\x03"),
+		MatcherFunc(func(u *Unreader) bool {
+			// reads past its prefix, which Detect rewinds
+			u.Discard(4)
+			return u.HasPrefix([]byte("!"))
+		}),
+	}
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"GET / HTTP/1.1\r\n", 0},
+		{"POST / HTTP/1.1\r\n", 0},
+		{"\x16\x03\x01\x00", 1},
+		{"PING!", 2},
+		{"PUT / HTTP/1.1\r\n", -1},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(64, strings.NewReader(tt.in))
+		if got := u.Detect(matchers...); got != tt.want || u.Cursor() != 0 {
+			t.Errorf("Detect(%q) = %d with cursor at %d, want %d", tt.in, got, u.Cursor(), tt.want)
+		}
+		if u.InTransaction() != 0 {
+			t.Errorf("Detect(%q) left %d regions open", tt.in, u.InTransaction())
+		}
+	}
+}
+
+func TestIsSSH(t *testing.T) {
+	for in, want := range map[string]bool{
+		"SSH-2.0-OpenSSH_9.6\r\n": true,
+		"SSH-1.99-Cisco-1.25\r\n": true,
+		"SSH-2.0-":                true,
+		"SSH-1.5-old\r\n":         false,
+		"SSH-2.0":                 false,
+		"ssh-2.0-lower\r\n":       false,
+		"GET / HTTP/1.1\r\n\r\n":  false,
+		"":                        false,
+	} {
+		u := NewUnreaderString(in)
+		if got := u.IsSSH(); got != want || u.Cursor() != 0 {
+			t.Errorf("IsSSH(%q) = %v with cursor at %d, want %v", in, got, u.Cursor(), want)
+		}
+	}
+}
+
+func TestDetectSSH(t *testing.T) {
+	u, _ := NewUnreader(32, strings.NewReader("SSH-2.0-OpenSSH\r\nrest"))
+	if i := u.Detect(MatchHTTP1(), MatchSSH()); i != 1 {
+		t.Fatalf("Detect() = %d, want 1 for MatchSSH", i)
+	}
+	if l, err := u.ReadLine(); string(l) != "SSH-2.0-OpenSSH" || err != nil {
+		t.Errorf("ReadLine() after Detect = %q, %v", l, err)
+	}
+}
+
+func TestIsSSHSilentPeer(t *testing.T) {
+	for _, in := range []string{"\x05\x01\x00", "SSH-1.5", "GET"} {
+		u := silentPeer(t, []byte(in))
+		got := true
+		if !returns(func() { got = u.IsSSH() }) {
+			t.Errorf("IsSSH() blocked on %q", in)
+		} else if got {
+			t.Errorf("IsSSH(%q) = true", in)
+		}
+	}
+	u := silentPeer(t, []byte{5, 1, 0})
+	i := -2
+	if !returns(func() { i = u.Detect(MatchSSH(), MatchSOCKS5()) }) || i != 1 {
+		t.Errorf("Detect() on a SOCKS5 greeting = %d, want 1", i)
+	}
+}
@@ -0,0 +1,46 @@
+package unreader
+
+import (
+	"bytes"
+
+	"golang.org/x/text/transform"
+)
+
+// newlineNormalizer is a transform.Transformer that converts "\r\n" and
+// lone "\r" to "\n".
+type newlineNormalizer struct {
+	transform.NopResetter
+}
+
+func (newlineNormalizer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		i := bytes.IndexByte(src[nSrc:], '\r')
+		if i < 0 {
+			i = len(src) - nSrc
+		}
+		n := copy(dst[nDst:], src[nSrc:nSrc+i])
+		nDst += n
+		nSrc += n
+		if n < i {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		if nSrc == len(src) {
+			break
+		}
+
+		// src[nSrc] is '\r'; whether it's followed by '\n' may not be known yet
+		if nSrc+1 == len(src) && !atEOF {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		if nDst == len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		dst[nDst] = '\n'
+		nDst++
+		nSrc++
+		if nSrc < len(src) && src[nSrc] == '\n' {
+			nSrc++
+		}
+	}
+	return nDst, nSrc, nil
+}
@@ -0,0 +1,188 @@
+package unreader
+
+import "strconv"
+
+// ReadInt reads the longest prefix of the upcoming bytes that is an integer
+// in the given base, with an optional sign, and parses it as by
+// strconv.ParseInt. The first byte after the number is left unread. It
+// returns ErrNoMatch if no number starts at the cursor, and leaves the
+// cursor where it was on any error, including a *strconv.NumError for a
+// value out of range.
+func (u *Unreader) ReadInt(base, bitSize int) (int64, error) {
+	s, err := u.peekNumber(func(b []byte, atEOF bool) int {
+		return intLen(b, atEOF, base, true)
+	})
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(s, base, bitSize)
+	if err != nil {
+		return 0, err
+	}
+	u.consumeNumber(s)
+	return v, nil
+}
+
+// ReadUint is like ReadInt but for an unsigned integer, parsed as by
+// strconv.ParseUint.
+func (u *Unreader) ReadUint(base, bitSize int) (uint64, error) {
+	s, err := u.peekNumber(func(b []byte, atEOF bool) int {
+		return intLen(b, atEOF, base, false)
+	})
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(s, base, bitSize)
+	if err != nil {
+		return 0, err
+	}
+	u.consumeNumber(s)
+	return v, nil
+}
+
+// ReadFloat is like ReadInt but for a decimal floating-point number, with
+// an optional fraction and exponent, parsed as by strconv.ParseFloat.
+func (u *Unreader) ReadFloat(bitSize int) (float64, error) {
+	s, err := u.peekNumber(floatLen)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(s, bitSize)
+	if err != nil {
+		return 0, err
+	}
+	u.consumeNumber(s)
+	return v, nil
+}
+
+// peekNumber returns the number at the cursor whose length is found by
+// end, without consuming it.
+func (u *Unreader) peekNumber(end func(b []byte, atEOF bool) int) (string, error) {
+	u.clearLast()
+	n, err := u.peekScan(0, func(b []byte, atEOF bool) int {
+		if len(b) == 0 && atEOF {
+			return -1
+		}
+		return end(b, atEOF)
+	})
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", ErrNoMatch
+	}
+	return string(u.replay()[:n]), nil
+}
+
+// consumeNumber advances past s, a number returned by peekNumber.
+func (u *Unreader) consumeNumber(s string) {
+	u.cursor += int64(len(s))
+	u.lastTokenSize = len(s)
+}
+
+// intLen returns the length of the longest integer in base at the start of
+// b, or -1 if the integer could continue past the end of b. Base 0 takes
+// the base from a 0x, 0o or 0b prefix, or a leading 0 for octal.
+func intLen(b []byte, atEOF bool, base int, signed bool) int {
+	i := 0
+	if signed && len(b) > 0 && (b[0] == '+' || b[0] == '-') {
+		i++
+	}
+	if base == 0 {
+		base = 10
+		if i < len(b) && b[i] == '0' {
+			if i+1 == len(b) {
+				if !atEOF {
+					return -1
+				}
+				return i + 1
+			}
+			switch b[i+1] | 0x20 {
+			case 'x':
+				base = 16
+			case 'o':
+				base = 8
+			case 'b':
+				base = 2
+			default:
+				base = 8
+			}
+			if base != 8 || b[i+1]|0x20 == 'o' {
+				// the prefix counts only if a digit follows it
+				if i+2 == len(b) && !atEOF {
+					return -1
+				}
+				if i+2 == len(b) || digitVal(b[i+2]) >= base {
+					return i + 1
+				}
+				i += 2
+			}
+		}
+	}
+	j := i
+	for j < len(b) && digitVal(b[j]) < base {
+		j++
+	}
+	if j == len(b) && !atEOF {
+		return -1
+	}
+	if j == i {
+		return 0
+	}
+	return j
+}
+
+// floatLen returns the length of the longest decimal floating-point number
+// at the start of b, or -1 if the number could continue past the end of b.
+func floatLen(b []byte, atEOF bool) int {
+	i := 0
+	digits := func() int {
+		start := i
+		for i < len(b) && '0' <= b[i] && b[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	if len(b) > 0 && (b[0] == '+' || b[0] == '-') {
+		i++
+	}
+	n := digits()
+	if i < len(b) && b[i] == '.' {
+		i++
+		n += digits()
+	}
+	if i == len(b) && !atEOF {
+		return -1
+	}
+	if n == 0 {
+		return 0
+	}
+	end := i
+	if i < len(b) && b[i]|0x20 == 'e' {
+		i++
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		start := i
+		digits()
+		if i == len(b) && !atEOF {
+			return -1
+		}
+		if i > start {
+			end = i
+		}
+	}
+	return end
+}
+
+// digitVal returns the value of c as a digit in bases up to 36, or 36 if it
+// isn't one.
+func digitVal(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c|0x20 && c|0x20 <= 'z':
+		return int(c|0x20-'a') + 10
+	}
+	return 36
+}
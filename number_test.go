@@ -0,0 +1,89 @@
+package unreader
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadInt(t *testing.T) {
+	tests := []struct {
+		in      string
+		base    int
+		bitSize int
+		want    int64
+		rest    string
+		err     error
+	}{
+		{"-42,", 0, 64, -42, ",", nil},
+		{"+7", 10, 64, 7, "", nil},
+		{"0x1fg", 0, 64, 31, "g", nil},
+		{"0755 ", 0, 64, 0755, " ", nil},
+		{"0b101", 0, 8, 5, "", nil},
+		{"ff!", 16, 64, 255, "!", nil},
+		{"99999999999", 10, 32, 0, "99999999999", strconv.ErrRange},
+		{"-x", 10, 64, 0, "-x", ErrNoMatch},
+		{"", 10, 64, 0, "", io.EOF},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, iotest.OneByteReader(strings.NewReader(tt.in)))
+		v, err := u.ReadInt(tt.base, tt.bitSize)
+		if v != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ReadInt(%q) = %d, %v, want %d, %v", tt.in, v, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("ReadInt(%q) left %q, want %q", tt.in, rest, tt.rest)
+		}
+	}
+}
+
+func TestReadUint(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint64
+		rest string
+		err  error
+	}{
+		{"18446744073709551615", 1<<64 - 1, "", nil},
+		{"12ab", 12, "ab", nil},
+		{"-1", 0, "-1", ErrNoMatch},
+	}
+	for _, tt := range tests {
+		u := NewUnreaderString(tt.in)
+		v, err := u.ReadUint(10, 64)
+		if v != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ReadUint(%q) = %d, %v, want %d, %v", tt.in, v, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("ReadUint(%q) left %q, want %q", tt.in, rest, tt.rest)
+		}
+	}
+}
+
+func TestReadFloat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		rest string
+		err  error
+	}{
+		{"1.5e3x", 1500, "x", nil},
+		{"-0.25 ", -0.25, " ", nil},
+		{"2e+", 2, "e+", nil},
+		{".5", 0.5, "", nil},
+		{".", 0, ".", ErrNoMatch},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, iotest.OneByteReader(strings.NewReader(tt.in)))
+		v, err := u.ReadFloat(64)
+		if v != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ReadFloat(%q) = %v, %v, want %v, %v", tt.in, v, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("ReadFloat(%q) left %q, want %q", tt.in, rest, tt.rest)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package unreader
+
+import (
+	"io"
+
+	"golang.org/x/text/transform"
+)
+
+// defaultBufferSize is the buffer size used by New when WithBufferSize is not
+// given.
+const defaultBufferSize = 4096
+
+type options struct {
+	size              int64
+	prefill           []byte
+	recording         bool
+	growable          bool
+	greedy            bool
+	stripBOM          bool
+	runeEnc           Encoding
+	strictRunes       bool
+	normalizeNewlines bool
+	maxTokenSize      int
+	tee               io.Writer
+
+	transforms []transform.Transformer
+}
+
+// Option configures an Unreader created by New.
+type Option func(*options)
+
+// WithBufferSize sets the size of the circular buffer, which bounds how far
+// back the Unreader can be unread.
+func WithBufferSize(size int64) Option {
+	return func(o *options) {
+		o.size = size
+	}
+}
+
+// WithPrefill places b in the buffer as if it had already been read from the
+// underlying reader, with the cursor before it. Reads return b before
+// anything from the underlying reader.
+func WithPrefill(b []byte) Option {
+	return func(o *options) {
+		o.prefill = b
+	}
+}
+
+// WithRecording controls whether bytes returned by Read are recorded in the
+// buffer. With recording off, bytes can still be peeked and unread within a
+// peek, but bytes read directly from the underlying reader can't be unread.
+// Recording is on by default.
+func WithRecording(on bool) Option {
+	return func(o *options) {
+		o.recording = on
+	}
+}
+
+// WithGrowable lets the buffer grow when an operation needs more room than
+// it has, such as a Peek larger than the buffer, instead of failing.
+func WithGrowable(on bool) Option {
+	return func(o *options) {
+		o.growable = on
+	}
+}
+
+// WithGreedyRead makes a Read that replays unread bytes continue with a read
+// from the underlying reader when p has room left, instead of returning only
+// the replayed bytes. The extra read may block.
+func WithGreedyRead(on bool) Option {
+	return func(o *options) {
+		o.greedy = on
+	}
+}
+
+// WithBOMStripping strips a UTF-8, UTF-16 or UTF-32 byte order mark from the
+// start of the underlying reader, recording the encoding it indicates for
+// Unreader.BOM. Stream offsets start after the mark, so unreads can reach
+// the first byte after it but never the mark itself.
+func WithBOMStripping(on bool) Option {
+	return func(o *options) {
+		o.stripBOM = on
+	}
+}
+
+// WithTransform applies the transformers, in order, to the underlying reader
+// before its bytes reach the buffer, such as to decode a charset or
+// normalize text. The cursor, unreads and offsets all work on the
+// transformed bytes. Any byte order mark is stripped before transforming.
+func WithTransform(t ...transform.Transformer) Option {
+	return func(o *options) {
+		o.transforms = append(o.transforms, t...)
+	}
+}
+
+// WithRuneEncoding sets the encoding ReadRune, PeekRune and UnreadRunes
+// decode: UTF8, UTF16BE, UTF16LE, UTF32BE or UTF32LE. By default, the
+// encoding indicated by a byte order mark stripped with WithBOMStripping is
+// used, falling back to UTF-8.
+func WithRuneEncoding(enc Encoding) Option {
+	return func(o *options) {
+		o.runeEnc = enc
+	}
+}
+
+// WithStrictRunes makes ReadRune and PeekRune return ErrInvalidRune for
+// invalid input, leaving the cursor before it, instead of decoding it as
+// utf8.RuneError like the standard library does.
+func WithStrictRunes(on bool) Option {
+	return func(o *options) {
+		o.strictRunes = on
+	}
+}
+
+// WithNewlineNormalization converts "\r\n" and lone "\r" in the underlying
+// reader to "\n" before they reach the buffer, after any transformers given
+// with WithTransform. The cursor, unreads and offsets all work on the
+// normalized bytes.
+func WithNewlineNormalization(on bool) Option {
+	return func(o *options) {
+		o.normalizeNewlines = on
+	}
+}
+
+// WithMaxTokenSize bounds the tokens returned by reads that seek a
+// delimiter or the end of a construct, such as ReadLine, ReadUntil,
+// ReadQuoted and Scan, to n bytes. A delimiter the read stops at, such as
+// the newline ending a line, doesn't count. Longer tokens fail with
+// ErrTokenTooLong instead of being buffered, so untrusted input can't make
+// them hold unbounded memory. 0, the default, means no limit: reads that
+// gather their token outside the buffer, such as ReadLine, ReadBytes,
+// ReadUntil and ReadRunesWhile, return tokens of any length, and only those
+// that need the whole token buffered, such as Scan and ReadQuoted, are
+// bounded by the buffer size.
+func WithMaxTokenSize(n int) Option {
+	return func(o *options) {
+		o.maxTokenSize = n
+	}
+}
+
+// WithTee mirrors the bytes read from the underlying reader to w, as
+// SetTee does.
+func WithTee(w io.Writer) Option {
+	return func(o *options) {
+		o.tee = w
+	}
+}
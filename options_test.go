@@ -0,0 +1,106 @@
+package unreader
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestMaxTokenSize(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+	reads := map[string]func(u *Unreader) (int, error){
+		"ReadLine": func(u *Unreader) (int, error) {
+			b, err := u.ReadLine()
+			return len(b), err
+		},
+		"ReadUntil": func(u *Unreader) (int, error) {
+			b, err := u.ReadUntil([]byte("\n"), true)
+			return len(b), err
+		},
+		"ReadRunesWhile": func(u *Unreader) (int, error) {
+			s, err := u.ReadRunesWhile(unicode.IsLetter)
+			return len(s), err
+		},
+	}
+	for name, read := range reads {
+		// with no limit, the token may be longer than the buffer
+		u, _ := New(strings.NewReader(long+"\n"), WithBufferSize(16))
+		if n, err := read(u); n != len(long) || err != nil {
+			t.Errorf("%s with no limit = %d bytes, %v, want %d", name, n, err, len(long))
+		}
+
+		u, _ = New(strings.NewReader(long+"\n"), WithBufferSize(16), WithMaxTokenSize(8))
+		if n, err := read(u); n != 8 || err != ErrTokenTooLong {
+			t.Errorf("%s with a limit of 8 = %d bytes, %v, want 8, ErrTokenTooLong", name, n, err)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		peek   int
+		want   string // after the peek
+		err    error
+		unread bool // whether the first 3 bytes read can be unread
+	}{
+		{"defaults", nil, 4, "worl", nil, true},
+		{"buffer size", []Option{WithBufferSize(2)}, 4, "wo", ErrBufferFull, false},
+		{"prefill", []Option{WithPrefill([]byte("hi "))}, 5, "hi wo", nil, true},
+		{"growable", []Option{WithBufferSize(2), WithGrowable(true)}, 4, "worl", nil, true},
+		{"grown prefill", []Option{WithBufferSize(2), WithPrefill([]byte("hi ")), WithGrowable(true)}, 5, "hi wo", nil, true},
+		{"no recording", []Option{WithRecording(false)}, 0, "", nil, false},
+	}
+	for _, tt := range tests {
+		u, err := New(strings.NewReader("world"), tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: New() = %v", tt.name, err)
+		}
+		if b, err := u.Peek(tt.peek); string(b) != tt.want || err != tt.err {
+			t.Errorf("%s: Peek(%d) = %q, %v, want %q, %v", tt.name, tt.peek, b, err, tt.want, tt.err)
+		}
+		u.ReadFull(make([]byte, 3))
+		if err := u.Unread(3); (err == nil) != tt.unread {
+			t.Errorf("%s: Unread(3) = %v", tt.name, err)
+		}
+	}
+
+	if _, err := New(strings.NewReader(""), WithBufferSize(2), WithPrefill([]byte("abc"))); err != ErrBufferFull {
+		t.Errorf("New() with a prefill larger than the buffer = %v, want ErrBufferFull", err)
+	}
+}
+
+func TestStrictRunes(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"stray byte", "\xffz"},
+		{"truncated", "\xe2\x82z"},
+		{"overlong", "\xc0\xafz"},
+		{"surrogate", "\xed\xa0\x80z"},
+	}
+	for _, tt := range tests {
+		// lenient: RuneError for each bad byte, like the standard library
+		u, _ := New(strings.NewReader(tt.in), WithBufferSize(16))
+		for i := 0; i < len(tt.in)-1; i++ {
+			if r, size, err := u.ReadRune(); r != utf8.RuneError || size != 1 || err != nil {
+				t.Fatalf("%s: ReadRune() = %q, %d, %v, want RuneError, 1", tt.name, r, size, err)
+			}
+		}
+		if r, _, _ := u.ReadRune(); r != 'z' {
+			t.Errorf("%s: ReadRune() after the bad bytes = %q", tt.name, r)
+		}
+
+		// strict: an error, with nothing consumed
+		u, _ = New(strings.NewReader(tt.in), WithBufferSize(16), WithStrictRunes(true))
+		if _, _, err := u.PeekRune(); err != ErrInvalidRune {
+			t.Errorf("%s: strict PeekRune() = %v, want ErrInvalidRune", tt.name, err)
+		}
+		if _, _, err := u.ReadRune(); err != ErrInvalidRune || u.Cursor() != 0 {
+			t.Errorf("%s: strict ReadRune() = %v, cursor %d", tt.name, err, u.Cursor())
+		}
+	}
+}
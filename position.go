@@ -0,0 +1,62 @@
+package unreader
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// Position is a location in the stream read by an Unreader.
+type Position struct {
+	Offset int64 // byte offset, starting at 0
+	Line   int   // line number, starting at 1
+	Column int   // column number in bytes, starting at 1
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Position returns the position of the cursor. It stays correct across
+// unreads, since lines are counted as bytes enter the buffer rather than as
+// they are read.
+func (u *Unreader) Position() Position {
+	// newlines at or after the cursor are always still tracked in nl
+	i := sort.Search(len(u.nl), func(i int) bool { return u.nl[i] >= u.cursor })
+	start := u.lineStart
+	if i > 0 {
+		start = u.nl[i-1] + 1
+	}
+	return Position{
+		Offset: u.cursor,
+		Line:   int(u.lines-int64(len(u.nl)-i)) + 1,
+		Column: int(u.cursor-start) + 1,
+	}
+}
+
+// track updates the line and rune bookkeeping for p, the bytes that were just added
+// to the end of the stream.
+func (u *Unreader) track(p []byte) {
+	base := u.bytesRead - int64(len(p))
+	u.runes += countRunes(p, base, u.countEncoding())
+	for i := 0; ; {
+		j := bytes.IndexByte(p[i:], '\n')
+		if j < 0 {
+			break
+		}
+		u.nl = append(u.nl, base+int64(i+j))
+		u.lines++
+		i += j + 1
+	}
+
+	// forget newlines the cursor can no longer be rewound past
+	start := u.bytesRead - u.retained()
+	k := 0
+	for k < len(u.nl) && u.nl[k] < start {
+		k++
+	}
+	if k > 0 {
+		u.lineStart = u.nl[k-1] + 1
+		u.nl = u.nl[k:]
+	}
+}
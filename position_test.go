@@ -0,0 +1,50 @@
+package unreader
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// TestPosition checks that Position stays correct as the cursor moves back
+// and forth across lines, including past bytes no longer buffered.
+func TestPosition(t *testing.T) {
+	u, _ := NewUnreader(6, iotest.OneByteReader(strings.NewReader("ab\ncd\nef\ngh")))
+	steps := []struct {
+		move func() error
+		want Position
+	}{
+		{func() error { return nil }, Position{0, 1, 1}},
+		{func() error { _, err := u.Discard(4); return err }, Position{4, 2, 2}},
+		{func() error { return u.Unread(3) }, Position{1, 1, 2}},
+		{func() error { return u.Unread(1) }, Position{0, 1, 1}},
+		{func() error { _, err := u.Discard(11); return err }, Position{11, 4, 3}},
+		{func() error { return u.Unread(5) }, Position{6, 3, 1}},
+		{func() error { return u.Unread(1) }, Position{5, 2, 3}},
+		{func() error { _, err := u.Discard(2); return err }, Position{7, 3, 2}},
+	}
+	for i, s := range steps {
+		if err := s.move(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if p := u.Position(); p != s.want {
+			t.Errorf("step %d: Position() = %+v, want %+v", i, p, s.want)
+		}
+	}
+	if s := u.Position().String(); s != "3:2" {
+		t.Errorf("Position().String() = %q, want 3:2", s)
+	}
+}
+
+func TestPositionUnreadRune(t *testing.T) {
+	u := NewUnreaderString("é\nx")
+	u.ReadRune()
+	u.ReadRune()
+	if p := u.Position(); p != (Position{3, 2, 1}) {
+		t.Fatalf("Position() = %+v, want line 2", p)
+	}
+	u.UnreadRune()
+	if p := u.Position(); p != (Position{2, 1, 3}) {
+		t.Errorf("Position() after UnreadRune = %+v, want 1:3", p)
+	}
+}
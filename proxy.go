@@ -0,0 +1,47 @@
+package unreader
+
+// SOCKSVersion returns 4 or 5 if the stream starts like a SOCKS4 or SOCKS5
+// client request, or 0 if it doesn't, without consuming anything. SOCKS4
+// requests start with version 4 and the CONNECT or BIND command; SOCKS5
+// greetings with version 5 and a non-empty list of authentication methods.
+func (u *Unreader) SOCKSVersion() int {
+	b := u.peekValid(2, func(b []byte) bool {
+		return len(b) == 0 || b[0] == 4 || b[0] == 5
+	})
+	if len(b) < 2 {
+		return 0
+	}
+	switch {
+	case b[0] == 4 && (b[1] == 1 || b[1] == 2):
+		return 4
+	case b[0] == 5 && b[1] > 0 && len(u.peekQuiet(2+int(b[1]))) == 2+int(b[1]):
+		return 5
+	}
+	return 0
+}
+
+// MatchSOCKS4 returns a Matcher for SOCKS4 client requests.
+func MatchSOCKS4() Matcher {
+	return MatcherFunc(func(u *Unreader) bool {
+		return u.SOCKSVersion() == 4
+	})
+}
+
+// MatchSOCKS5 returns a Matcher for SOCKS5 client greetings.
+func MatchSOCKS5() Matcher {
+	return MatcherFunc(func(u *Unreader) bool {
+		return u.SOCKSVersion() == 5
+	})
+}
+
+// IsConnect reports whether the stream starts with an HTTP CONNECT request
+// line, as sent to a proxy to open a tunnel, without consuming anything.
+func (u *Unreader) IsConnect() bool {
+	rl, err := u.PeekRequestLine()
+	return err == nil && rl.Method == "CONNECT"
+}
+
+// MatchConnect returns a Matcher for HTTP CONNECT requests.
+func MatchConnect() Matcher {
+	return MatcherFunc((*Unreader).IsConnect)
+}
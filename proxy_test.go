@@ -0,0 +1,88 @@
+package unreader
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSOCKSVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"\x04\x01\x00\x50\x7f\x00\x00\x01\x00", 4},
+		{"\x04\x02", 4},
+		{"\x04\x03", 0},
+		{"\x05\x02\x00\x02", 5},
+		{"\x05\x02\x00", 0},
+		{"\x05\x00", 0},
+		{"\x05", 0},
+		{"GET / HTTP/1.1\r\n", 0},
+	}
+	for _, tt := range tests {
+		u := NewUnreaderString(tt.in)
+		if got := u.SOCKSVersion(); got != tt.want {
+			t.Errorf("SOCKSVersion(% x) = %d, want %d", tt.in, got, tt.want)
+		}
+		if u.Cursor() != 0 {
+			t.Errorf("SOCKSVersion(% x) consumed %d bytes", tt.in, u.Cursor())
+		}
+	}
+}
+
+func TestIsConnect(t *testing.T) {
+	for in, want := range map[string]bool{
+		"CONNECT example.com:443 HTTP/1.1\r\n": true,
+		"CONNECT example.com:443 HTTP/1.1":     false,
+		"GET http://example.com/ HTTP/1.1\r\n": false,
+		"CONNECTX x HTTP/1.1\r\n":              false,
+		"\x05\x01\x00":                         false,
+	} {
+		u, _ := NewUnreader(64, strings.NewReader(in))
+		if got := u.IsConnect(); got != want {
+			t.Errorf("IsConnect(%q) = %v", in, got)
+		}
+	}
+}
+
+// TestDetectSilentPeer checks that no matcher waits on a peer that sent a
+// short first message and is waiting for a reply, whichever runs first.
+func TestDetectSilentPeer(t *testing.T) {
+	names := []string{"connect", "http1", "http2", "websocket", "socks4", "socks5", "ssh", "postgres", "mysql", "prefix"}
+	matchers := []Matcher{
+		MatchConnect(), MatchHTTP1(), MatchHTTP2(), MatchWebSocket(0), MatchSOCKS4(),
+		MatchSOCKS5(), MatchSSH(), MatchPostgres(), MatchMySQL(), MatchPrefix("PROXY "),
+	}
+	tests := []struct {
+		in   []byte
+		want []string // names of the matchers that may match
+	}{
+		{[]byte{5, 1, 0}, []string{"socks5"}},
+		{[]byte{4, 1, 0, 80, 127, 0, 0, 1, 0}, []string{"socks4"}},
+		{[]byte("SSH-2.0-x\r\n"), []string{"ssh"}},
+		{[]byte("CONNECT x:443 HTTP/1.1\r\n\r\n"), []string{"connect", "http1"}},
+		{[]byte("GET / HTTP/1.0\r\n\r\n"), []string{"http1"}},
+		{[]byte("\x00\x00\x00\x08\x04\xd2\x16\x2f"), []string{"postgres"}},
+		{[]byte{0x16, 3, 1}, []string{""}},
+	}
+	for _, tt := range tests {
+		for i, first := range matchers {
+			ms := append([]Matcher{first}, slices.Delete(slices.Clone(matchers), i, i+1)...)
+			order := append([]string{names[i]}, slices.Delete(slices.Clone(names), i, i+1)...)
+			u := silentPeer(t, tt.in)
+			got := -2
+			if !returns(func() { got = u.Detect(ms...) }) {
+				t.Errorf("Detect(% x) with %s first blocked", tt.in, names[i])
+				continue
+			}
+			name := ""
+			if got >= 0 {
+				name = order[got]
+			}
+			if !slices.Contains(tt.want, name) {
+				t.Errorf("Detect(% x) with %s first matched %q, want one of %q", tt.in, names[i], name, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package unreader
+
+import (
+	"io"
+	"sync"
+)
+
+// StartReadahead starts a goroutine that reads ahead from the underlying
+// reader, keeping up to high bytes that the Unreader hasn't taken yet
+// ready, so that parsing overlaps with waiting for the reader. A high of
+// zero or less means the buffer size. Reads, peeks and unreads work as
+// before, and take the prefetched bytes first. Calling StartReadahead
+// again changes the high-water mark.
+func (u *Unreader) StartReadahead(high int) {
+	if high <= 0 {
+		high = int(u.cb.Size())
+	}
+	if ra, ok := u.rd.(*readahead); ok && !ra.stopped() {
+		ra.setHigh(high)
+		return
+	}
+	ra := &readahead{r: u.rd, high: high, running: true}
+	ra.cond = sync.NewCond(&ra.mu)
+	u.rd = ra
+	go ra.run()
+}
+
+// StopReadahead stops reading ahead. A read already in flight is left to
+// finish, and the bytes prefetched so far are still returned, in order,
+// before the underlying reader is read directly again. It returns how many
+// bytes were prefetched and not yet taken.
+func (u *Unreader) StopReadahead() int {
+	ra, ok := u.rd.(*readahead)
+	if !ok {
+		return 0
+	}
+	return ra.stop()
+}
+
+// readahead reads r in a goroutine into a queue of up to high bytes.
+type readahead struct {
+	r io.Reader
+
+	mu      sync.Mutex
+	cond    *sync.Cond // signaled when the queue or state changes
+	buf     []byte     // prefetched bytes not yet read
+	high    int        // most bytes to prefetch
+	err     error      // error that ended the goroutine, returned once buf is empty
+	quit    bool       // StopReadahead was called
+	running bool       // the goroutine hasn't exited
+}
+
+func (ra *readahead) run() {
+	p := make([]byte, fillSize)
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	for {
+		for len(ra.buf) >= ra.high && !ra.quit {
+			ra.cond.Wait()
+		}
+		if ra.quit {
+			break
+		}
+		want := min(ra.high-len(ra.buf), len(p))
+		ra.mu.Unlock()
+		n, err := ra.r.Read(p[:want])
+		ra.mu.Lock()
+		ra.buf = append(ra.buf, p[:n]...)
+		if err != nil {
+			ra.err = err
+			break
+		}
+		ra.cond.Broadcast()
+	}
+	ra.running = false
+	ra.cond.Broadcast()
+}
+
+func (ra *readahead) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	ra.mu.Lock()
+	for len(ra.buf) == 0 && ra.err == nil && ra.running {
+		ra.cond.Wait()
+	}
+	if len(ra.buf) > 0 {
+		n := copy(p, ra.buf)
+		ra.buf = ra.buf[:copy(ra.buf, ra.buf[n:])]
+		ra.cond.Broadcast()
+		ra.mu.Unlock()
+		return n, nil
+	}
+	if ra.err != nil {
+		err := ra.err
+		ra.err = nil
+		ra.mu.Unlock()
+		return 0, err
+	}
+	// stopped and drained, so r is no longer read by the goroutine
+	ra.mu.Unlock()
+	return ra.r.Read(p)
+}
+
+func (ra *readahead) setHigh(high int) {
+	ra.mu.Lock()
+	ra.high = high
+	ra.cond.Broadcast()
+	ra.mu.Unlock()
+}
+
+func (ra *readahead) stop() int {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.quit = true
+	ra.cond.Broadcast()
+	return len(ra.buf)
+}
+
+func (ra *readahead) stopped() bool {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	return ra.quit
+}
+
+// Close stops reading ahead and closes r if it implements io.Closer, which
+// also ends a read in flight for most readers.
+func (ra *readahead) Close() error {
+	ra.stop()
+	if c, ok := ra.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
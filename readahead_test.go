@@ -0,0 +1,55 @@
+package unreader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// countingReader records how many bytes have been read from it.
+type countingReader struct {
+	r io.Reader
+	n chan int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n <- n
+	return n, err
+}
+
+func TestReadahead(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 20)
+	cr := &countingReader{r: bytes.NewReader(data), n: make(chan int, 100)}
+	u, _ := NewUnreader(16, cr)
+	u.StartReadahead(32)
+
+	// the goroutine reads up to the high-water mark without being asked
+	total := 0
+	for total < 32 {
+		select {
+		case n := <-cr.n:
+			total += n
+		case <-time.After(time.Second):
+			t.Fatalf("read ahead %d bytes, want 32", total)
+		}
+	}
+	select {
+	case n := <-cr.n:
+		t.Fatalf("read %d bytes past the high-water mark", n)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	b := make([]byte, 10)
+	u.ReadFull(b)
+	u.Unread(5)
+	if p, err := u.Peek(10); err != nil || string(p) != "5678901234" {
+		t.Fatalf("Peek after Unread = %q, %v", p, err)
+	}
+	u.StopReadahead()
+	got, err := io.ReadAll(u)
+	if err != nil || !bytes.Equal(got, data[5:]) {
+		t.Fatalf("ReadAll after StopReadahead = %d bytes, %v", len(got), err)
+	}
+}
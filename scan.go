@@ -0,0 +1,485 @@
+package unreader
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ReadWhile reads bytes while pred returns true, returning a copy of them
+// and leaving the first byte that doesn't match unread. The error is non-nil
+// only if reading stopped before a non-matching byte, such as io.EOF at the
+// end of the stream or ErrTokenTooLong past the maximum token size. The
+// bytes can be pushed back with UnreadToken.
+func (u *Unreader) ReadWhile(pred func(byte) bool) (b []byte, err error) {
+	_, err = u.bytesWhile(spanFunc(pred), &b)
+	return b, err
+}
+
+// SkipWhile is like ReadWhile but discards the bytes, returning how many
+// were skipped.
+func (u *Unreader) SkipWhile(pred func(byte) bool) (n int, err error) {
+	return u.bytesWhile(spanFunc(pred), nil)
+}
+
+// ReadUntilAny reads up to but not including the first byte that is in
+// chars, returning a copy of the bytes read. Errors are reported as by
+// ReadWhile, and the bytes can be pushed back with UnreadToken.
+func (u *Unreader) ReadUntilAny(chars []byte) (b []byte, err error) {
+	var stop [256]bool
+	for _, c := range chars {
+		stop[c] = true
+	}
+	_, err = u.bytesWhile(func(buf []byte) int {
+		for i, c := range buf {
+			if stop[c] {
+				return i
+			}
+		}
+		return len(buf)
+	}, &b)
+	return b, err
+}
+
+// spanFunc returns a function giving the length of the prefix of its input
+// whose bytes match pred.
+func spanFunc(pred func(byte) bool) func([]byte) int {
+	return func(b []byte) int {
+		i := 0
+		for i < len(b) && pred(b[i]) {
+			i++
+		}
+		return i
+	}
+}
+
+// bytesWhile advances past bytes as long as span takes all of those
+// buffered, appending them to dst if it isn't nil. span returns how many
+// bytes at the start of its input to take.
+func (u *Unreader) bytesWhile(span func([]byte) int, dst *[]byte) (n int, err error) {
+	u.clearLast()
+	for {
+		buf := u.replay()
+		i := span(buf)
+		if dst != nil {
+			*dst = append(*dst, buf[:i]...)
+		}
+		u.cursor += int64(i)
+		n += i
+		if dst != nil {
+			if *dst, err = u.capToken(*dst); err != nil {
+				n = len(*dst)
+				break
+			}
+		}
+		if i < len(buf) {
+			break
+		}
+		if _, err = u.fill(min(fillSize, int(u.cb.Size()))); u.cursor == u.bytesRead {
+			break
+		}
+		if err != nil {
+			u.err = err
+			err = nil
+		}
+	}
+	u.lastTokenSize = n
+	return n, err
+}
+
+// ReadRunesWhile reads runes while pred returns true, returning them as a
+// string and leaving the first rune that doesn't match unread. Errors are
+// reported as by ReadWhile, and the runes can be pushed back with
+// UnreadToken.
+func (u *Unreader) ReadRunesWhile(pred func(rune) bool) (string, error) {
+	var sb strings.Builder
+	_, err := u.runesWhile(pred, &sb)
+	return sb.String(), err
+}
+
+// SkipSpace skips runes for which unicode.IsSpace is true, returning how
+// many bytes were skipped.
+func (u *Unreader) SkipSpace() (n int, err error) {
+	return u.runesWhile(unicode.IsSpace, nil)
+}
+
+// runesWhile advances past runes matching pred, writing them to sb if it
+// isn't nil, and returns the number of bytes advanced.
+func (u *Unreader) runesWhile(pred func(rune) bool, sb *strings.Builder) (n int, err error) {
+	u.clearLast()
+	for {
+		r, size, perr := u.PeekRune()
+		if perr != nil {
+			err = perr
+			break
+		}
+		if !pred(r) {
+			break
+		}
+		if sb != nil {
+			if u.maxToken > 0 && n+size > u.maxToken {
+				err = ErrTokenTooLong
+				break
+			}
+			sb.WriteRune(r)
+		}
+		u.cursor += int64(size)
+		n += size
+	}
+	u.lastTokenSize = n
+	return n, err
+}
+
+// peekQuiet is like Peek for callers that can't report an error. Any error
+// is kept to be returned by the next read instead.
+func (u *Unreader) peekQuiet(n int) []byte {
+	b, err := u.Peek(n)
+	if err != nil && err != ErrBufferFull {
+		u.err = err
+	}
+	return b
+}
+
+// peekValid is like peekQuiet, but stops waiting for more bytes as soon as
+// valid reports that those so far can't begin what the caller is looking
+// for, returning fewer than n.
+func (u *Unreader) peekValid(n int, valid func(b []byte) bool) []byte {
+	if int64(n) > u.cb.Size() {
+		return u.peekQuiet(n)
+	}
+	for {
+		b := u.replay()
+		if len(b) >= n {
+			return b[:n]
+		}
+		if !valid(b) {
+			return b
+		}
+		if _, err := u.fill(n - len(b)); err != nil {
+			if err != ErrBufferFull {
+				u.err = err
+			}
+			return u.replay()
+		}
+	}
+}
+
+// HasPrefix reports whether the next bytes are prefix, without consuming
+// them. Bytes are compared as they arrive, so it returns false as soon as
+// one differs rather than waiting for all of prefix.
+func (u *Unreader) HasPrefix(prefix []byte) bool {
+	if int64(len(prefix)) > u.cb.Size() {
+		if !u.growable {
+			return false
+		}
+		u.grow(int64(len(prefix)))
+	}
+	for {
+		b := u.replay()
+		n := min(len(b), len(prefix))
+		if !bytes.Equal(b[:n], prefix[:n]) {
+			return false
+		}
+		if n == len(prefix) {
+			return true
+		}
+		if _, err := u.fill(len(prefix) - n); err != nil {
+			if err != ErrBufferFull {
+				u.err = err
+			}
+			return false
+		}
+	}
+}
+
+// Expect consumes prefix if the next bytes match it and reports whether they
+// did. On a mismatch the cursor is left where it was.
+func (u *Unreader) Expect(prefix []byte) bool {
+	if !u.HasPrefix(prefix) {
+		return false
+	}
+	u.clearLast()
+	u.cursor += int64(len(prefix))
+	return true
+}
+
+// HasPrefixFold is like HasPrefix but compares as strings.EqualFold does,
+// treating the upcoming bytes and prefix as UTF-8 that is equal under
+// Unicode case folding. The matching bytes may differ in length from
+// prefix.
+func (u *Unreader) HasPrefixFold(prefix []byte) bool {
+	_, ok := u.foldPrefix(prefix)
+	return ok
+}
+
+// ExpectFold is like Expect but compares as HasPrefixFold does.
+func (u *Unreader) ExpectFold(prefix []byte) bool {
+	n, ok := u.foldPrefix(prefix)
+	if !ok {
+		return false
+	}
+	u.clearLast()
+	u.cursor += int64(n)
+	return true
+}
+
+// foldPrefix reports whether the upcoming bytes match prefix under case
+// folding, and how many bytes matched. It peeks only as far as it needs to.
+func (u *Unreader) foldPrefix(prefix []byte) (n int, ok bool) {
+	for len(prefix) > 0 {
+		b := u.peekQuiet(n + 1)
+		for len(b) > n && len(b) < n+utf8.UTFMax && !utf8.FullRune(b[n:]) {
+			more := u.peekQuiet(len(b) + 1)
+			if len(more) == len(b) {
+				break
+			}
+			b = more
+		}
+		if len(b) <= n {
+			return 0, false
+		}
+		r, size := utf8.DecodeRune(b[n:])
+		pr, psize := utf8.DecodeRune(prefix)
+		if r == utf8.RuneError && size == 1 || pr == utf8.RuneError && psize == 1 {
+			// invalid bytes match only themselves
+			if size != psize || b[n] != prefix[0] {
+				return 0, false
+			}
+		} else if !equalFold(r, pr) {
+			return 0, false
+		}
+		n += size
+		prefix = prefix[psize:]
+	}
+	return n, true
+}
+
+// equalFold reports whether r and s are equal under simple Unicode case
+// folding.
+func equalFold(r, s rune) bool {
+	if r == s {
+		return true
+	}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f == s {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchRegexp tries to match re against the upcoming bytes, anchored at the
+// cursor, looking at no more than max bytes. On a match it consumes and
+// returns a copy of the longest match; otherwise the cursor is left where it
+// was. Matches that would continue past max bytes are cut off at max.
+func (u *Unreader) MatchRegexp(re *regexp.Regexp, max int) ([]byte, bool) {
+	b := u.peekQuiet(max)
+	// a leftmost-longest match starting at 0 is the longest anchored one
+	longest := *re
+	longest.Longest()
+	loc := longest.FindIndex(b)
+	if loc == nil || loc[0] != 0 {
+		return nil, false
+	}
+	m := append([]byte(nil), b[:loc[1]]...)
+	u.clearLast()
+	u.cursor += int64(loc[1])
+	u.lastTokenSize = loc[1]
+	return m, true
+}
+
+// peekScan fills the buffer until end finds where a construct at the
+// cursor ends, without consuming it. end is given the bytes after the
+// cursor, up to the token limit or max of them if max is positive, and
+// whether the stream ends after them; it returns the length of the
+// construct, or -1 if it needs more bytes. If the stream ends first,
+// peekScan returns io.ErrUnexpectedEOF, or io.EOF if there were no bytes at
+// all.
+func (u *Unreader) peekScan(max int, end func(b []byte, atEOF bool) int) (int, error) {
+	limit, tooLong := u.tokenLimit()
+	if max > 0 && max < limit {
+		limit, tooLong = max, ErrTokenTooLong
+	}
+	rerr := u.err
+	u.err = nil
+	for {
+		b := u.replay()
+		atEOF := rerr == io.EOF && len(b) <= limit
+		full := len(b) >= limit
+		if full {
+			b = b[:limit]
+		}
+		if n := end(b, atEOF); n >= 0 || full && !atEOF {
+			if rerr != nil {
+				u.err = rerr
+			}
+			if n < 0 {
+				return n, tooLong
+			}
+			return n, nil
+		}
+		if rerr == io.EOF {
+			u.err = rerr
+			if len(b) == 0 {
+				return -1, io.EOF
+			}
+			return -1, io.ErrUnexpectedEOF
+		}
+		if rerr != nil {
+			return -1, rerr
+		}
+		if int64(len(b)) >= u.cb.Size() {
+			if !u.growable {
+				return -1, ErrBufferFull
+			}
+			u.grow(2 * u.cb.Size())
+		}
+		_, rerr = u.fill(min(fillSize, int(u.cb.Size())-len(b)))
+	}
+}
+
+// tokenLimit returns the most bytes a token can span, and the error for one
+// that would span more.
+func (u *Unreader) tokenLimit() (int, error) {
+	limit := math.MaxInt
+	if !u.growable {
+		limit = int(u.cb.Size())
+	}
+	if u.maxToken > 0 && u.maxToken <= limit {
+		return u.maxToken, ErrTokenTooLong
+	}
+	return limit, ErrBufferFull
+}
+
+// ReadQuoted reads a string enclosed by open and close, returning its
+// contents with the quotes removed and escapes resolved. An escape byte
+// makes the byte after it literal; if escape is the same as close, a
+// doubled close quote stands for one, as in SQL and CSV. The whole string
+// must fit in the buffer: it returns ErrNoMatch if the next byte isn't
+// open, ErrBufferFull if the closing quote isn't within the buffer,
+// ErrTokenTooLong if it isn't within the maximum token size and
+// io.ErrUnexpectedEOF if the stream ends first, leaving the cursor where it
+// was in each case. The string can be pushed back with UnreadToken.
+func (u *Unreader) ReadQuoted(open, close, escape byte) ([]byte, error) {
+	u.clearLast()
+	b, err := u.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] != open {
+		return nil, ErrNoMatch
+	}
+	var s []byte
+	n, err := u.peekScan(0, func(b []byte, atEOF bool) int {
+		s = s[:0]
+		for i := 1; i < len(b); i++ {
+			c := b[i]
+			switch {
+			case c == escape && escape == close:
+				if i+1 == len(b) {
+					if atEOF {
+						return i + 1
+					}
+					return -1
+				}
+				if b[i+1] != close {
+					return i + 1
+				}
+				i++
+			case c == escape:
+				if i+1 == len(b) {
+					return -1
+				}
+				i++
+				c = b[i]
+			case c == close:
+				return i + 1
+			}
+			s = append(s, c)
+		}
+		return -1
+	})
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	u.cursor += int64(n)
+	u.lastTokenSize = n
+	return s, nil
+}
+
+// QuoteSyntax describes the quoting inside a region read by ReadBalanced.
+// Delimiters inside quotes or after an escape don't count toward nesting.
+type QuoteSyntax struct {
+	Quotes string // runes that each open and close a quoted string
+	Escape rune   // makes the rune after it literal; 0 for none
+}
+
+// ReadBalanced reads a region of UTF-8 text that starts with open and ends
+// at the close that balances it, counting nested pairs and skipping quoted
+// text and escapes as described by q. It returns a copy of the region,
+// including its delimiters, which can be pushed back with UnreadToken. open
+// and close must differ.
+//
+// At most max bytes are examined, if max is positive, and no more than the
+// maximum token size; the region must also fit in the buffer. ReadBalanced
+// returns ErrNoMatch if the next rune isn't open, ErrTokenTooLong or
+// ErrBufferFull if the region doesn't close within those limits and
+// io.ErrUnexpectedEOF if the stream ends first, leaving the cursor where it
+// was in each case.
+func (u *Unreader) ReadBalanced(open, close rune, q QuoteSyntax, max int) ([]byte, error) {
+	u.clearLast()
+	n, err := u.peekScan(max, func(b []byte, atEOF bool) int {
+		if len(b) == 0 && atEOF {
+			return -1
+		}
+		depth, quote := 0, rune(0)
+		for i := 0; i < len(b); {
+			if !utf8.FullRune(b[i:]) && !atEOF {
+				return -1
+			}
+			r, size := utf8.DecodeRune(b[i:])
+			if i == 0 && r != open {
+				return 0
+			}
+			i += size
+			switch {
+			case q.Escape != 0 && r == q.Escape:
+				if i == len(b) || !utf8.FullRune(b[i:]) && !atEOF {
+					return -1
+				}
+				_, size = utf8.DecodeRune(b[i:])
+				i += size
+			case quote != 0:
+				if r == quote {
+					quote = 0
+				}
+			case strings.ContainsRune(q.Quotes, r):
+				quote = r
+			case r == open:
+				depth++
+			case r == close:
+				if depth--; depth == 0 {
+					return i
+				}
+			}
+		}
+		return -1
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNoMatch
+	}
+	b := append([]byte(nil), u.replay()[:n]...)
+	u.cursor += int64(n)
+	u.lastTokenSize = n
+	return b, nil
+}
@@ -0,0 +1,351 @@
+package unreader
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+	"unicode"
+)
+
+// silentPeer returns an Unreader over a connection whose peer has sent b
+// and is now waiting for a reply. The connection is closed at the end of
+// the test.
+func silentPeer(t *testing.T, b []byte) *Unreader {
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	go client.Write(b)
+	u, _ := NewUnreader(64, server)
+	for !bytes.Equal(u.PeekAvailable(), b) {
+		u.FillTimeout(time.Second)
+	}
+	return u
+}
+
+// returns reports whether f returns within a second.
+func returns(f func()) bool {
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestHasPrefix(t *testing.T) {
+	for _, tt := range []struct {
+		in, prefix string
+		want       bool
+	}{
+		{"hello world", "hello", true},
+		{"hello", "hello", true},
+		{"hello", "", true},
+		{"help", "hello", false},
+		{"hel", "hello", false},
+		{"", "h", false},
+	} {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		if got := u.HasPrefix([]byte(tt.prefix)); got != tt.want || u.Cursor() != 0 {
+			t.Errorf("HasPrefix(%q) on %q = %v with cursor at %d, want %v", tt.prefix, tt.in, got, u.Cursor(), tt.want)
+		}
+	}
+	if NewUnreaderString("abc").HasPrefix([]byte("abcd")) {
+		t.Error("HasPrefix() longer than the buffer = true")
+	}
+}
+
+func TestExpect(t *testing.T) {
+	tests := []struct {
+		in, prefix string
+		want       bool
+		rest       string
+	}{
+		{"hello world", "hello", true, " world"},
+		{"hello", "hello", true, ""},
+		{"hello", "", true, "hello"},
+		{"help", "hello", false, "help"},
+		{"hel", "hello", false, "hel"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		if got := u.Expect([]byte(tt.prefix)); got != tt.want {
+			t.Errorf("Expect(%q) on %q = %v, want %v", tt.prefix, tt.in, got, tt.want)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("Expect(%q) on %q left %q, want %q", tt.prefix, tt.in, rest, tt.rest)
+		}
+	}
+}
+
+func TestHasPrefixSilentPeer(t *testing.T) {
+	u := silentPeer(t, []byte("GET"))
+	var got bool
+	if !returns(func() { got = u.HasPrefix([]byte("PRI * HTTP/2.0")) }) {
+		t.Fatal("HasPrefix() waited for bytes after a mismatch")
+	}
+	if got {
+		t.Error("HasPrefix() = true")
+	}
+}
+
+func TestMatchRegexp(t *testing.T) {
+	tests := []struct {
+		in, re string
+		max    int
+		want   string
+		ok     bool
+	}{
+		{"abc123 def", `[a-z]+|[a-z]+[0-9]+`, 64, "abc123", true},
+		{"abc123 def", `def`, 64, "", false},
+		{"abc123 def", `[0-9]+`, 64, "", false},
+		{"aaaaaaaa", `a+`, 3, "aaa", true},
+		{"", `x*`, 8, "", true},
+	}
+	for _, tt := range tests {
+		u := NewUnreaderString(tt.in)
+		m, ok := u.MatchRegexp(regexp.MustCompile(tt.re), tt.max)
+		if string(m) != tt.want || ok != tt.ok {
+			t.Errorf("MatchRegexp(%q) on %q = %q, %v, want %q, %v", tt.re, tt.in, m, ok, tt.want, tt.ok)
+		}
+		if u.Cursor() != int64(len(tt.want)) {
+			t.Errorf("MatchRegexp(%q) on %q left the cursor at %d", tt.re, tt.in, u.Cursor())
+		}
+	}
+
+	// the caller's regexp keeps its leftmost-first semantics
+	re := regexp.MustCompile(`a|ab`)
+	NewUnreaderString("ab").MatchRegexp(re, 8)
+	if m := re.FindString("ab"); m != "a" {
+		t.Errorf("FindString() after MatchRegexp = %q, want a", m)
+	}
+}
+
+func TestReadWhile(t *testing.T) {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	tests := []struct {
+		name string
+		in   io.Reader
+		want string
+		err  error
+		rest string
+	}{
+		{"stops at mismatch", strings.NewReader("123abc"), "123", nil, "abc"},
+		{"no match", strings.NewReader("abc"), "", nil, "abc"},
+		{"to EOF", strings.NewReader("123"), "123", io.EOF, ""},
+		{"across reads", iotest.OneByteReader(strings.NewReader("12345x")), "12345", nil, "x"},
+		{"longer than the buffer", strings.NewReader(strings.Repeat("7", 40) + "x"), strings.Repeat("7", 40), nil, "x"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, tt.in)
+		b, err := u.ReadWhile(isDigit)
+		if string(b) != tt.want || err != tt.err {
+			t.Errorf("%s: ReadWhile() = %q, %v, want %q, %v", tt.name, b, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+
+	u := NewUnreaderString("42;")
+	u.ReadWhile(isDigit)
+	if err := u.UnreadToken(); err != nil || u.Cursor() != 0 {
+		t.Fatalf("UnreadToken() after ReadWhile = %v, cursor %d", err, u.Cursor())
+	}
+}
+
+func TestReadRunesWhile(t *testing.T) {
+	tests := []struct {
+		name string
+		in   io.Reader
+		want string
+		err  error
+		rest string
+	}{
+		{"identifier", strings.NewReader("héllo wörld"), "héllo", nil, " wörld"},
+		{"no match", strings.NewReader(" x"), "", nil, " x"},
+		{"to EOF", strings.NewReader("日本語"), "日本語", io.EOF, ""},
+		{"rune split across reads", iotest.OneByteReader(strings.NewReader("ñu!")), "ñu", nil, "!"},
+		{"stops at non-letter rune", strings.NewReader("ab€c"), "ab", nil, "€c"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, tt.in)
+		s, err := u.ReadRunesWhile(unicode.IsLetter)
+		if s != tt.want || err != tt.err {
+			t.Errorf("%s: ReadRunesWhile() = %q, %v, want %q, %v", tt.name, s, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestSkipWhile(t *testing.T) {
+	tests := []struct {
+		name string
+		skip func(u *Unreader) (int, error)
+		in   string
+		n    int
+		err  error
+		rest string
+	}{
+		{"bytes", func(u *Unreader) (int, error) { return u.SkipWhile(func(c byte) bool { return c == '-' }) }, "---x", 3, nil, "x"},
+		{"no match", func(u *Unreader) (int, error) { return u.SkipWhile(func(c byte) bool { return c == '-' }) }, "x--", 0, nil, "x--"},
+		{"bytes to EOF", func(u *Unreader) (int, error) { return u.SkipWhile(func(c byte) bool { return c == '-' }) }, "--", 2, io.EOF, ""},
+		{"space", (*Unreader).SkipSpace, " \t\r\nx ", 4, nil, "x "},
+		{"unicode space", (*Unreader).SkipSpace, "\u00a0\u2003x", 5, nil, "x"},
+		{"space to EOF", (*Unreader).SkipSpace, "  ", 2, io.EOF, ""},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		if n, err := tt.skip(u); n != tt.n || err != tt.err {
+			t.Errorf("%s: skipped %d, %v, want %d, %v", tt.name, n, err, tt.n, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestReadQuoted(t *testing.T) {
+	tests := []struct {
+		name                string
+		in                  string
+		open, close, escape byte
+		want                string
+		err                 error
+		rest                string
+	}{
+		{"plain", `"abc" x`, '"', '"', '\\', "abc", nil, " x"},
+		{"escaped quote", `"a\"b" x`, '"', '"', '\\', `a"b`, nil, " x"},
+		{"escaped escape", `"a\\" x`, '"', '"', '\\', `a\`, nil, " x"},
+		{"doubled quote", `'it''s' x`, '\'', '\'', '\'', "it's", nil, " x"},
+		{"brackets", "[a]b]", '[', ']', 0, "a", nil, "b]"},
+		{"empty", `"" x`, '"', '"', '\\', "", nil, " x"},
+		{"not quoted", `abc"`, '"', '"', '\\', "", ErrNoMatch, `abc"`},
+		{"unterminated", `"abc`, '"', '"', '\\', "", io.ErrUnexpectedEOF, `"abc`},
+		{"past the buffer", `"` + strings.Repeat("x", 20) + `"`, '"', '"', '\\', "", ErrBufferFull, `"` + strings.Repeat("x", 20) + `"`},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		s, err := u.ReadQuoted(tt.open, tt.close, tt.escape)
+		if string(s) != tt.want || err != tt.err {
+			t.Errorf("%s: ReadQuoted() = %q, %v, want %q, %v", tt.name, s, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestExpectFold(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		prefix string
+		ok     bool
+		rest   string // after ExpectFold
+	}{
+		{"same case", "GET /", "GET", true, " /"},
+		{"other case", "get /", "GET", true, " /"},
+		{"mixed", "Content-Type: x", "content-type:", true, " x"},
+		{"unicode", "ÉCOLE x", "école", true, " x"},
+		{"different length", "ſ!", "S", true, "!"},
+		{"mismatch", "POST /", "GET", false, "POST /"},
+		{"short", "GE", "GET", false, "GE"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		if ok := u.HasPrefixFold([]byte(tt.prefix)); ok != tt.ok || u.Cursor() != 0 {
+			t.Errorf("%s: HasPrefixFold(%q) = %v, cursor %d", tt.name, tt.prefix, ok, u.Cursor())
+		}
+		if ok := u.ExpectFold([]byte(tt.prefix)); ok != tt.ok {
+			t.Errorf("%s: ExpectFold(%q) = %v, want %v", tt.name, tt.prefix, ok, tt.ok)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestHasPrefixFoldSilentPeer(t *testing.T) {
+	u := silentPeer(t, []byte("PUT"))
+	var got bool
+	if !returns(func() { got = u.HasPrefixFold([]byte("PROPFIND")) }) {
+		t.Fatal("HasPrefixFold() waited for bytes after a mismatch")
+	}
+	if got {
+		t.Error("HasPrefixFold() = true")
+	}
+}
+
+func TestReadBalanced(t *testing.T) {
+	code := QuoteSyntax{Quotes: `"'`, Escape: '\\'}
+	tests := []struct {
+		name string
+		in   string
+		q    QuoteSyntax
+		max  int
+		want string
+		err  error
+		rest string
+	}{
+		{"flat", "{a} x", QuoteSyntax{}, 0, "{a}", nil, " x"},
+		{"nested", "{a{b}{c{d}}} x", QuoteSyntax{}, 0, "{a{b}{c{d}}}", nil, " x"},
+		{"quoted close", `{"}"} x`, code, 0, `{"}"}`, nil, " x"},
+		{"escaped close", `{\}} x`, code, 0, `{\}}`, nil, " x"},
+		{"not open", "a{}", QuoteSyntax{}, 0, "", ErrNoMatch, "a{}"},
+		{"unclosed", "{a{b}", QuoteSyntax{}, 0, "", io.ErrUnexpectedEOF, "{a{b}"},
+		{"past max", "{abcdef}", QuoteSyntax{}, 4, "", ErrTokenTooLong, "{abcdef}"},
+		{"past the buffer", "{" + strings.Repeat("x", 40) + "}", QuoteSyntax{}, 0, "", ErrBufferFull, "{" + strings.Repeat("x", 40) + "}"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(32, strings.NewReader(tt.in))
+		b, err := u.ReadBalanced('{', '}', tt.q, tt.max)
+		if string(b) != tt.want || err != tt.err {
+			t.Errorf("%s: ReadBalanced() = %q, %v, want %q, %v", tt.name, b, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestReadUntilAny(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    io.Reader
+		chars string
+		want  string
+		err   error
+		rest  string
+	}{
+		{"first of several", strings.NewReader(`key=value,x`), ",=\n", "key", nil, "=value,x"},
+		{"at cursor", strings.NewReader(",x"), ",", "", nil, ",x"},
+		{"none", strings.NewReader("abc"), ",", "abc", io.EOF, ""},
+		{"empty set", strings.NewReader("abc"), "", "abc", io.EOF, ""},
+		{"high bytes", strings.NewReader("ab\xffc"), "\xff", "ab", nil, "\xffc"},
+		{"across reads", iotest.OneByteReader(strings.NewReader("abcd\n")), "\n\"", "abcd", nil, "\n"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, tt.in)
+		b, err := u.ReadUntilAny([]byte(tt.chars))
+		if string(b) != tt.want || err != tt.err {
+			t.Errorf("%s: ReadUntilAny(%q) = %q, %v, want %q, %v", tt.name, tt.chars, b, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package unreader
+
+import "io"
+
+// Snapshot is a copy of the bytes an Unreader held in its buffer at one
+// point, along with their absolute stream offsets. Later reads don't affect
+// it, and its methods never expose its bytes for modification.
+type Snapshot struct {
+	data   []byte
+	start  int64
+	cursor int64
+}
+
+// Snapshot returns a copy of the bytes currently held in the buffer.
+func (u *Unreader) Snapshot() Snapshot {
+	return Snapshot{
+		data:   append([]byte(nil), u.bytes()...),
+		start:  u.bytesRead - u.retained(),
+		cursor: u.cursor,
+	}
+}
+
+// Start returns the stream offset of the first byte in the snapshot.
+func (s Snapshot) Start() int64 {
+	return s.start
+}
+
+// End returns the stream offset just after the last byte in the snapshot,
+// which was BytesRead when it was taken.
+func (s Snapshot) End() int64 {
+	return s.start + int64(len(s.data))
+}
+
+// Cursor returns the cursor when the snapshot was taken.
+func (s Snapshot) Cursor() int64 {
+	return s.cursor
+}
+
+// Len returns the number of bytes in the snapshot.
+func (s Snapshot) Len() int {
+	return len(s.data)
+}
+
+// Bytes returns a copy of the bytes in the snapshot.
+func (s Snapshot) Bytes() []byte {
+	return append([]byte(nil), s.data...)
+}
+
+// ReadAt implements io.ReaderAt for absolute stream offsets, returning
+// ErrEvicted for offsets before Start and io.EOF for reads past End.
+func (s Snapshot) ReadAt(p []byte, off int64) (int, error) {
+	if off < s.start {
+		return 0, ErrEvicted
+	}
+	if off >= s.End() {
+		return 0, io.EOF
+	}
+	n := copy(p, s.data[off-s.start:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
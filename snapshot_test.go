@@ -0,0 +1,46 @@
+package unreader
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	u, _ := NewUnreader(8, strings.NewReader("0123456789abcdef"))
+	u.Discard(10)
+	u.Unread(2)
+	s := u.Snapshot()
+	io.ReadAll(u)
+
+	if s.Start() != 2 || s.End() != 10 || s.Cursor() != 8 || s.Len() != 8 {
+		t.Fatalf("snapshot covers %d-%d, cursor %d, len %d", s.Start(), s.End(), s.Cursor(), s.Len())
+	}
+	b := s.Bytes()
+	if string(b) != "23456789" {
+		t.Fatalf("Bytes = %q", b)
+	}
+	b[0] = 'x'
+	if string(s.Bytes()) != "23456789" {
+		t.Fatal("modifying Bytes changed the snapshot")
+	}
+
+	tests := []struct {
+		off  int64
+		n    int
+		want string
+		err  error
+	}{
+		{2, 3, "234", nil},
+		{8, 4, "89", io.EOF},
+		{10, 1, "", io.EOF},
+		{1, 1, "", ErrEvicted},
+	}
+	for _, tt := range tests {
+		p := make([]byte, tt.n)
+		n, err := s.ReadAt(p, tt.off)
+		if string(p[:n]) != tt.want || err != tt.err {
+			t.Errorf("ReadAt(%d, %d) = %q, %v; want %q, %v", tt.n, tt.off, p[:n], err, tt.want, tt.err)
+		}
+	}
+}
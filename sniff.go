@@ -0,0 +1,36 @@
+package unreader
+
+import (
+	"io"
+	"net/http"
+)
+
+// sniffLen is how many bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// Sniff returns a copy of up to the next n bytes without consuming them,
+// for identifying what the stream holds. Unlike Peek, a stream that ends
+// before n bytes, or a buffer too small for them, isn't an error: Sniff
+// returns what there is. Other errors are returned with the bytes peeked
+// before them.
+func (u *Unreader) Sniff(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+	b, err := u.Peek(n)
+	if err == io.EOF || err == ErrBufferFull {
+		err = nil
+	}
+	return append([]byte(nil), b...), err
+}
+
+// DetectContentType returns the MIME type of the stream as determined by
+// http.DetectContentType from up to its first 512 bytes after the cursor,
+// which are left unread.
+func (u *Unreader) DetectContentType() (string, error) {
+	b, err := u.Sniff(sniffLen)
+	if err != nil {
+		return "", err
+	}
+	return http.DetectContentType(b), nil
+}
@@ -0,0 +1,62 @@
+package unreader
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		n    int
+		want string
+		err  error
+	}{
+		{"within the stream", "hello world", 5, "hello", nil},
+		{"stream ends first", "hi", 5, "hi", nil},
+		{"buffer too small", "hello world", 20, "hello wo", nil},
+		{"negative", "hi", -1, "", ErrNegativeCount},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(8, strings.NewReader(tt.in))
+		b, err := u.Sniff(tt.n)
+		if string(b) != tt.want || err != tt.err {
+			t.Errorf("%s: Sniff(%d) = %q, %v, want %q, %v", tt.name, tt.n, b, err, tt.want, tt.err)
+		}
+		if u.Cursor() != 0 {
+			t.Errorf("%s: Sniff moved the cursor to %d", tt.name, u.Cursor())
+		}
+		// the result is a copy, so later reads don't change it
+		if len(b) > 0 {
+			u.Read(make([]byte, 8))
+			if string(b) != tt.want {
+				t.Errorf("%s: sniffed bytes changed to %q", tt.name, b)
+			}
+		}
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"html", "<!DOCTYPE html><html></html>", "text/html; charset=utf-8"},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"gzip", "\x1f\x8b\x08\x00", "application/x-gzip"},
+		{"text", "plain text", "text/plain; charset=utf-8"},
+		{"empty", "", "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(1024, strings.NewReader(tt.in))
+		if ct, err := u.DetectContentType(); ct != tt.want || err != nil {
+			t.Errorf("%s: DetectContentType() = %q, %v, want %q", tt.name, ct, err, tt.want)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.in {
+			t.Errorf("%s: left %q, want the whole stream", tt.name, rest)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package unreader
+
+import (
+	"io"
+	"os"
+)
+
+// SpillReader is an io.ReadSeeker over the stream read by an Unreader, for
+// callers such as http.ServeContent that seek anywhere in their input.
+// Bytes that would be evicted from the Unreader's buffer are first written
+// to a temporary file, so every offset stays reachable. Offsets start at
+// the Unreader's cursor when the SpillReader was created, and seeking to
+// the end reads the rest of the stream.
+type SpillReader struct {
+	u       *Unreader
+	dir     string
+	f       *os.File // bytes from base to spilled
+	base    int64    // stream offset of offset 0
+	spilled int64    // stream offset up to which bytes are in f
+	off     int64    // stream offset of the next Read
+	size    int64    // stream offset of the end, or -1 until it's reached
+}
+
+// NewSpillReader returns a SpillReader reading from u, keeping evicted
+// bytes in a temporary file created in dir, or in os.TempDir if dir is
+// empty. The file is only created once bytes need to be spilled. u must
+// not be used directly after this.
+func NewSpillReader(u *Unreader, dir string) *SpillReader {
+	s := &SpillReader{u: u, dir: dir, base: u.cursor, spilled: u.cursor, off: u.cursor, size: -1}
+	u.clearLast()
+	// the cursor must stay at the end so that fill can't evict unread bytes
+	u.cursor = u.bytesRead
+	return s
+}
+
+// Read implements io.Reader.
+func (s *SpillReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	u := s.u
+	for s.off >= u.bytesRead {
+		if s.size >= 0 {
+			return 0, io.EOF
+		}
+		if err := s.pull(); err != nil {
+			return 0, err
+		}
+	}
+	start := u.bytesRead - u.retained()
+	if s.off < start {
+		n, err := s.f.ReadAt(p[:min(int64(len(p)), s.spilled-s.off)], s.off-s.base)
+		s.off += int64(n)
+		return n, err
+	}
+	n := copy(p, u.bytes()[s.off-start:])
+	s.off += int64(n)
+	return n, nil
+}
+
+// Seek implements io.Seeker. Seeking relative to the end, or past the bytes
+// read so far, reads from the Unreader up to the new offset.
+func (s *SpillReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = s.base + offset
+	case io.SeekCurrent:
+		abs = s.off + offset
+	case io.SeekEnd:
+		for s.size < 0 {
+			if err := s.pull(); err != nil {
+				return s.off - s.base, err
+			}
+		}
+		abs = s.size + offset
+	default:
+		return s.off - s.base, ErrInvalidWhence
+	}
+	if abs < s.base {
+		return s.off - s.base, ErrNegativePosition
+	}
+	s.off = abs
+	return abs - s.base, nil
+}
+
+// Close removes the temporary file. It doesn't close the Unreader.
+func (s *SpillReader) Close() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	if rerr := os.Remove(s.f.Name()); err == nil {
+		err = rerr
+	}
+	s.f = nil
+	return err
+}
+
+// pull reads more of the stream into the Unreader's buffer, spilling the
+// bytes that would be evicted first. It records where the stream ends.
+func (s *SpillReader) pull() error {
+	u := s.u
+	k := min(fillSize, u.cb.Size())
+	if err := s.spill(u.bytesRead + k - u.cb.Size()); err != nil {
+		return err
+	}
+	_, err := u.fill(int(k))
+	u.cursor = u.bytesRead
+	if err == io.EOF {
+		s.size = u.bytesRead
+		return nil
+	}
+	return err
+}
+
+// spill writes the buffered bytes up to stream offset end to the file.
+func (s *SpillReader) spill(end int64) error {
+	if end <= s.spilled {
+		return nil
+	}
+	if s.f == nil {
+		f, err := os.CreateTemp(s.dir, "unreader-spill-*")
+		if err != nil {
+			return err
+		}
+		s.f = f
+	}
+	b := s.u.bytes()
+	start := s.u.bytesRead - int64(len(b))
+	n, err := s.f.WriteAt(b[s.spilled-start:end-start], s.spilled-s.base)
+	s.spilled += int64(n)
+	return err
+}
@@ -0,0 +1,62 @@
+package unreader
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpillReader(t *testing.T) {
+	data := strings.Repeat("0123456789", 100)
+	u, _ := NewUnreader(16, strings.NewReader("xx"+data))
+	u.Discard(2)
+	dir := t.TempDir()
+	s := NewSpillReader(u, dir)
+
+	if n, err := s.Seek(0, io.SeekEnd); n != int64(len(data)) || err != nil {
+		t.Fatalf("Seek to end = %d, %v", n, err)
+	}
+	for _, off := range []int64{0, 7, 500, 990, 999} {
+		if _, err := s.Seek(off, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 10)
+		n, _ := io.ReadFull(s, b)
+		if want := data[off:min(off+10, int64(len(data)))]; string(b[:n]) != want {
+			t.Errorf("at %d read %q, want %q", off, b[:n], want)
+		}
+	}
+	if _, err := s.Seek(-1, io.SeekStart); err != ErrNegativePosition {
+		t.Errorf("Seek before start error = %v, want ErrNegativePosition", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("%d spill files", len(entries))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ = os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("spill file left after Close")
+	}
+}
+
+func TestSpillReaderServeContent(t *testing.T) {
+	data := strings.Repeat("abcdefghij", 50)
+	u, _ := NewUnreader(32, strings.NewReader(data))
+	s := NewSpillReader(u, t.TempDir())
+	defer s.Close()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Range", "bytes=100-109")
+	rec := httptest.NewRecorder()
+	http.ServeContent(rec, req, "data.txt", time.Time{}, s)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != data[100:110] {
+		t.Fatalf("ServeContent = %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,128 @@
+package unreader
+
+import "sync"
+
+// SyncUnreader guards an Unreader with a mutex, so that one goroutine can
+// fill or inspect the stream while another consumes it. Each method holds
+// the lock for the whole call, including any read of the underlying reader
+// it makes, so a call that blocks on the reader blocks the others. Use Do
+// to run several operations, or any other method of the Unreader, as one.
+type SyncUnreader struct {
+	mu sync.Mutex
+	u  *Unreader
+}
+
+// NewSyncUnreader returns a SyncUnreader guarding u. u must not be used
+// directly afterwards.
+func NewSyncUnreader(u *Unreader) *SyncUnreader {
+	return &SyncUnreader{u: u}
+}
+
+// Do calls f with the Unreader while holding the lock. Slices returned by
+// the Unreader, such as from Peek, must not be kept after f returns.
+func (s *SyncUnreader) Do(f func(u *Unreader)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.u)
+}
+
+// Read is like Unreader.Read.
+func (s *SyncUnreader) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.Read(p)
+}
+
+// ReadByte is like Unreader.ReadByte.
+func (s *SyncUnreader) ReadByte() (byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.ReadByte()
+}
+
+// ReadRune is like Unreader.ReadRune. UnreadRune only succeeds if no other
+// operation took the lock in between.
+func (s *SyncUnreader) ReadRune() (r rune, size int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.ReadRune()
+}
+
+// UnreadRune is like Unreader.UnreadRune.
+func (s *SyncUnreader) UnreadRune() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.UnreadRune()
+}
+
+// UnreadByte is like Unreader.UnreadByte.
+func (s *SyncUnreader) UnreadByte() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.UnreadByte()
+}
+
+// Unread is like Unreader.Unread.
+func (s *SyncUnreader) Unread(c int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.Unread(c)
+}
+
+// Peek is like Unreader.Peek, but returns a copy of the bytes, since
+// another goroutine may read as soon as the lock is released.
+func (s *SyncUnreader) Peek(n int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := s.u.Peek(n)
+	return append([]byte(nil), b...), err
+}
+
+// Discard is like Unreader.Discard.
+func (s *SyncUnreader) Discard(n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.Discard(n)
+}
+
+// Advance is like Unreader.Advance.
+func (s *SyncUnreader) Advance(n int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.Advance(n)
+}
+
+// Cursor is like Unreader.Cursor.
+func (s *SyncUnreader) Cursor() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.Cursor()
+}
+
+// BytesRead is like Unreader.BytesRead.
+func (s *SyncUnreader) BytesRead() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.BytesRead()
+}
+
+// Buffered is like Unreader.Buffered.
+func (s *SyncUnreader) Buffered() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.Buffered()
+}
+
+// MaxUnread is like Unreader.MaxUnread.
+func (s *SyncUnreader) MaxUnread() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.MaxUnread()
+}
+
+// Close is like Unreader.Close.
+func (s *SyncUnreader) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.u.Close()
+}
@@ -0,0 +1,96 @@
+package unreader
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSyncUnreader(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	u, _ := NewUnreader(64, bytes.NewReader(data))
+	s := NewSyncUnreader(u)
+
+	// one goroutine peeks and unreads while another consumes
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			s.Do(func(u *Unreader) {
+				if b, err := u.Peek(4); err == nil {
+					u.Discard(2)
+					u.Unread(2)
+					if c := u.Cursor() % 10; b[0] != byte('0'+c) {
+						t.Errorf("peeked %q at cursor %d", b, u.Cursor())
+					}
+				}
+			})
+			s.Buffered()
+		}
+	}()
+	got, err := io.ReadAll(s)
+	close(done)
+	wg.Wait()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("ReadAll read %d bytes, %v", len(got), err)
+	}
+}
+
+func TestSyncUnreaderMethods(t *testing.T) {
+	u, _ := NewUnreader(8, strings.NewReader("héllo world"))
+	s := NewSyncUnreader(u)
+	var _ io.ReadCloser = s
+	var _ io.RuneScanner = s
+	var _ io.ByteScanner = s
+
+	steps := []struct {
+		name string
+		op   func() error
+		want int64 // cursor afterwards
+	}{
+		{"ReadByte", func() error { _, err := s.ReadByte(); return err }, 1},
+		{"UnreadByte", s.UnreadByte, 0},
+		{"ReadRune", func() error { _, _, err := s.ReadRune(); return err }, 1},
+		{"ReadRune multibyte", func() error { _, _, err := s.ReadRune(); return err }, 3},
+		{"UnreadRune", s.UnreadRune, 1},
+		{"Discard", func() error { _, err := s.Discard(5); return err }, 6},
+		{"Unread", func() error { return s.Unread(3) }, 3},
+		{"Advance", func() error { return s.Advance(2) }, 5},
+		{"Read", func() error { _, err := s.Read(make([]byte, 1)); return err }, 6},
+	}
+	for _, step := range steps {
+		if err := step.op(); err != nil || s.Cursor() != step.want {
+			t.Fatalf("%s: err %v, cursor %d, want %d", step.name, err, s.Cursor(), step.want)
+		}
+	}
+	if s.BytesRead() != 8 || s.Buffered() != 2 || s.MaxUnread() != 6 {
+		t.Fatalf("BytesRead, Buffered, MaxUnread = %d, %d, %d", s.BytesRead(), s.Buffered(), s.MaxUnread())
+	}
+
+	// Peek returns a copy that later reads don't overwrite
+	b, err := s.Peek(3)
+	if string(b) != " wo" || err != nil {
+		t.Fatalf("Peek(3) = %q, %v", b, err)
+	}
+	s.Read(make([]byte, 8))
+	s.Peek(3)
+	if string(b) != " wo" {
+		t.Fatalf("peeked bytes changed to %q", b)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if _, err := s.ReadByte(); err != ErrClosed {
+		t.Fatalf("ReadByte() after Close = %v, want ErrClosed", err)
+	}
+}
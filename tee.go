@@ -0,0 +1,25 @@
+package unreader
+
+import "io"
+
+// SetTee mirrors the bytes read from the underlying reader from now on to
+// w, or stops mirroring if w is nil. Each byte is written once, when it is
+// first read, however often it is unread and replayed, and bytes peeked
+// without being read are written too. A write error stops the mirroring,
+// and is returned by the next read that needs the underlying reader.
+func (u *Unreader) SetTee(w io.Writer) {
+	u.tee = w
+}
+
+// mirror writes p, just read from the underlying reader, to the tee.
+func (u *Unreader) mirror(p []byte) {
+	if u.tee == nil || len(p) == 0 {
+		return
+	}
+	if _, err := u.tee.Write(p); err != nil {
+		u.tee = nil
+		if u.err == nil {
+			u.err = err
+		}
+	}
+}
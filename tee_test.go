@@ -0,0 +1,53 @@
+package unreader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	var tee bytes.Buffer
+	u, _ := New(strings.NewReader("hello, world"), WithBufferSize(8), WithTee(&tee), WithPrefill([]byte(">")))
+	u.Peek(4)
+	u.Discard(3)
+	u.Unread(3)
+	b := make([]byte, 4)
+	u.Read(b)
+	u.Unread(4)
+	u.Read(b)
+	if tee.String() != "hel" {
+		t.Fatalf("tee after replays = %q", tee.String())
+	}
+
+	u.SetTee(nil)
+	u.Read(b[:2])
+	var rest bytes.Buffer
+	u.SetTee(&rest)
+	io.ReadAll(u)
+	if tee.String() != "hel" || rest.String() != ", world" {
+		t.Fatalf("tee after SetTee = %q, %q", tee.String(), rest.String())
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestTeeError(t *testing.T) {
+	u, _ := New(strings.NewReader("abcdef"), WithBufferSize(4), WithTee(failWriter{}))
+	b := make([]byte, 2)
+	if n, err := u.Read(b); n != 2 || err != nil {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+	if _, err := u.Read(b); err == nil || err.Error() != "disk full" {
+		t.Fatalf("Read after failed write = %v", err)
+	}
+	if got, err := io.ReadAll(u); string(got) != "cdef" || err != nil {
+		t.Fatalf("ReadAll after failed write = %q, %v", got, err)
+	}
+}
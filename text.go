@@ -0,0 +1,398 @@
+package unreader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// readDelim reads until the first occurrence of delim, returning a copy of
+// the bytes read including delim. If delim isn't found before an error, it
+// returns the bytes read and the error, or if it isn't within the maximum
+// token size, that many bytes and ErrTokenTooLong.
+func (u *Unreader) readDelim(delim byte) (line []byte, err error) {
+	u.clearLast()
+	for {
+		if b := u.replay(); len(b) > 0 {
+			i := bytes.IndexByte(b, delim)
+			if i < 0 {
+				i = len(b)
+			}
+			line = append(line, b[:i]...)
+			u.cursor += int64(i)
+			if line, err = u.capToken(line); err != nil {
+				return line, err
+			}
+			if i < len(b) {
+				line = append(line, delim)
+				u.cursor++
+				return line, nil
+			}
+		}
+		_, err = u.fill(min(fillSize, int(u.cb.Size())))
+		if u.cursor == u.bytesRead {
+			return line, err
+		}
+		if err != nil {
+			u.err = err
+		}
+	}
+}
+
+// capToken cuts tok, whose bytes end at the cursor, to the maximum token
+// size, moving the cursor back to match. It returns ErrTokenTooLong if tok
+// was cut.
+func (u *Unreader) capToken(tok []byte) ([]byte, error) {
+	if u.maxToken <= 0 || len(tok) <= u.maxToken {
+		return tok, nil
+	}
+	u.cursor -= int64(len(tok) - u.maxToken)
+	return tok[:u.maxToken], ErrTokenTooLong
+}
+
+// ReadLine reads a line, not including the end-of-line bytes ("\n" or
+// "\r\n"). A final line without a newline is returned with a nil error, and
+// the next call returns io.EOF. The whole line, including its terminator,
+// can be pushed back with UnreadLine. A line longer than the maximum token
+// size is cut short with ErrTokenTooLong, leaving the rest unread.
+func (u *Unreader) ReadLine() ([]byte, error) {
+	line, err := u.readDelim('\n')
+	if len(line) == 0 {
+		return nil, err
+	}
+	if err == ErrTokenTooLong {
+		u.lastTokenSize = len(line)
+		return line, err
+	}
+	if err == io.EOF {
+		u.err = err
+		err = nil
+	}
+	u.lastTokenSize = len(line)
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return line, err
+}
+
+// UnreadLine unreads the line returned by the last ReadLine, including its
+// terminator. It returns ErrInvalidUnreadLine if the last operation was not
+// a successful ReadLine.
+func (u *Unreader) UnreadLine() error {
+	if u.lastTokenSize <= 0 {
+		return ErrInvalidUnreadLine
+	}
+	return u.Unread(int64(u.lastTokenSize))
+}
+
+// ReadBytes reads until the first occurrence of delim, returning a copy of
+// the bytes read including delim. Like bufio.Reader.ReadBytes, it returns an
+// error if and only if the bytes don't end in delim. The token can be pushed
+// back as a whole with UnreadToken.
+func (u *Unreader) ReadBytes(delim byte) ([]byte, error) {
+	b, err := u.readDelim(delim)
+	u.lastTokenSize = len(b)
+	return b, err
+}
+
+// ReadString is like ReadBytes but returns a string.
+func (u *Unreader) ReadString(delim byte) (string, error) {
+	b, err := u.ReadBytes(delim)
+	return string(b), err
+}
+
+// UnreadToken unreads the bytes returned by the last ReadBytes, ReadString,
+// ReadSlice or ReadLine, including any delimiter. It returns ErrInvalidUnreadToken if
+// the last operation was not one of those.
+func (u *Unreader) UnreadToken() error {
+	if u.lastTokenSize <= 0 {
+		return ErrInvalidUnreadToken
+	}
+	return u.Unread(int64(u.lastTokenSize))
+}
+
+// ReadSlice reads until the first occurrence of delim, returning a slice of
+// the buffer up to and including delim instead of a copy. The slice is only
+// valid until the next read or peek, which may overwrite it, so it must not
+// be retained. If the buffer fills without finding delim, ReadSlice returns
+// the whole buffer and ErrBufferFull, unless the buffer is growable, and if
+// delim isn't within the maximum token size it returns that many bytes and
+// ErrTokenTooLong. Like ReadBytes, it returns an error if and only if line
+// doesn't end in delim.
+func (u *Unreader) ReadSlice(delim byte) (line []byte, err error) {
+	u.clearLast()
+	line, err = u.peekDelim(delim)
+	u.cursor += int64(len(line))
+	u.lastTokenSize = len(line)
+	return line, err
+}
+
+// peekDelim returns the buffered bytes up to and including the first delim
+// after the cursor, filling the buffer as needed but not moving the cursor.
+// If the buffer fills first, it returns the whole buffer and ErrBufferFull,
+// unless the buffer is growable.
+func (u *Unreader) peekDelim(delim byte) (line []byte, err error) {
+	var rerr error
+	for searched := 0; ; {
+		b := u.replay()
+		if i := bytes.IndexByte(b[searched:], delim); i >= 0 {
+			line = b[:searched+i+1]
+			if u.maxToken > 0 && searched+i > u.maxToken {
+				line, err = b[:u.maxToken], ErrTokenTooLong
+			}
+			break
+		}
+		searched = len(b)
+		if u.maxToken > 0 && len(b) > u.maxToken {
+			line, err = b[:u.maxToken], ErrTokenTooLong
+			break
+		}
+		if rerr != nil {
+			line, err = b, rerr
+			break
+		}
+		if int64(len(b)) >= u.cb.Size() {
+			if !u.growable {
+				line, err = b, ErrBufferFull
+				if u.maxToken > 0 && len(b) >= u.maxToken {
+					line, err = b[:u.maxToken], ErrTokenTooLong
+				}
+				break
+			}
+			u.grow(2 * u.cb.Size())
+		}
+		_, rerr = u.fill(min(fillSize, int(u.cb.Size())-len(b)))
+	}
+	if rerr != nil && err != rerr {
+		u.err = rerr
+	}
+	return line, err
+}
+
+// PeekLine returns the next line without consuming it, not including the
+// end-of-line bytes. As with ReadLine, a final line without a newline is
+// returned with a nil error. If no newline is found within the buffer,
+// PeekLine returns the buffered bytes and ErrBufferFull, unless the buffer
+// is growable. The line is only valid until the next read.
+func (u *Unreader) PeekLine() ([]byte, error) {
+	line, err := u.peekDelim('\n')
+	if err == io.EOF && len(line) > 0 {
+		u.err = err
+		err = nil
+	}
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return line, err
+}
+
+// PeekToken returns the next whitespace-delimited word without consuming
+// it, skipping any whitespace before it. It returns ErrBufferFull if the
+// word doesn't fit in the buffer, ErrTokenTooLong if it's longer than the
+// maximum token size and io.EOF if only whitespace remains. The word is
+// only valid until the next read.
+func (u *Unreader) PeekToken() ([]byte, error) {
+	_, word, err := u.peekToken()
+	return word, err
+}
+
+// ReadToken is like PeekToken but consumes the word and the whitespace
+// before it, returning a copy of the word. The whitespace after it is left
+// unread. Both can be pushed back with UnreadToken.
+func (u *Unreader) ReadToken() ([]byte, error) {
+	u.clearLast()
+	n, word, err := u.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	word = append([]byte(nil), word...)
+	u.cursor += int64(n)
+	u.lastTokenSize = n
+	return word, nil
+}
+
+// peekToken finds the next word, returning it and the number of bytes up
+// to its end.
+func (u *Unreader) peekToken() (n int, word []byte, err error) {
+	n, err = u.peekScan(0, func(b []byte, atEOF bool) int {
+		inWord := false
+		for i := 0; i < len(b); {
+			if !utf8.FullRune(b[i:]) && !atEOF {
+				return -1
+			}
+			r, size := utf8.DecodeRune(b[i:])
+			if !unicode.IsSpace(r) {
+				inWord = true
+			} else if inWord {
+				return i
+			}
+			i += size
+		}
+		if atEOF && len(b) > 0 {
+			return len(b)
+		}
+		return -1
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	word = bytes.TrimLeftFunc(u.replay()[:n], unicode.IsSpace)
+	if len(word) == 0 {
+		return 0, nil, io.EOF
+	}
+	return n, word, nil
+}
+
+// ReadUntil reads until the first occurrence of delim, returning a copy of
+// the bytes before it. If skip is true the cursor is left after delim,
+// otherwise before it. The search streams through the buffer, holding back
+// only the last len(delim)-1 bytes between reads of the underlying reader,
+// so the result may be longer than the buffer. If delim isn't found before
+// an error, ReadUntil returns the bytes read and the error. The bytes
+// returned, plus delim if skipped, can be pushed back with UnreadToken.
+func (u *Unreader) ReadUntil(delim []byte, skip bool) (b []byte, err error) {
+	u.clearLast()
+	if len(delim) == 0 {
+		return nil, nil
+	}
+	if int64(len(delim)) > u.cb.Size() {
+		if !u.growable {
+			return nil, ErrBufferFull
+		}
+		u.grow(int64(len(delim)))
+	}
+	for {
+		buf := u.replay()
+		if i := bytes.Index(buf, delim); i >= 0 {
+			if err != nil {
+				// keep the error that came with delim for the next read
+				u.err = err
+			}
+			b = append(b, buf[:i]...)
+			u.cursor += int64(i)
+			if b, err = u.capToken(b); err != nil {
+				u.lastTokenSize = len(b)
+				return b, err
+			}
+			if skip {
+				u.cursor += int64(len(delim))
+				u.lastTokenSize = len(b) + len(delim)
+			} else {
+				u.lastTokenSize = len(b)
+			}
+			return b, nil
+		}
+		if err != nil {
+			b = append(b, buf...)
+			u.cursor += int64(len(buf))
+			u.lastTokenSize = len(b)
+			return b, err
+		}
+		// keep what could be the start of delim
+		if k := len(buf) - (len(delim) - 1); k > 0 {
+			b = append(b, buf[:k]...)
+			u.cursor += int64(k)
+			if b, err = u.capToken(b); err != nil {
+				u.lastTokenSize = len(b)
+				return b, err
+			}
+		}
+		_, err = u.fill(min(fillSize, int(u.cb.Size()-u.Buffered())))
+	}
+}
+
+// Index returns the offset from the cursor of the first occurrence of
+// pattern within the next limit bytes, or -1 if there is none, without
+// consuming anything. It reads from the underlying reader only while the
+// pattern hasn't been found, and limit is capped at the buffer size. If the
+// stream ends or fails before limit bytes, Index returns -1 and the error.
+// A negative limit returns ErrNegativeCount.
+func (u *Unreader) Index(pattern []byte, limit int) (int, error) {
+	if limit < 0 {
+		return -1, ErrNegativeCount
+	}
+	limit = min(limit, int(u.cb.Size()))
+	var err error
+	for {
+		b := u.replay()
+		if len(b) > limit {
+			b = b[:limit]
+		}
+		if i := bytes.Index(b, pattern); i >= 0 {
+			if err != nil {
+				u.err = err
+			}
+			return i, nil
+		}
+		if len(b) >= limit {
+			return -1, nil
+		}
+		if err != nil {
+			return -1, err
+		}
+		_, err = u.fill(min(fillSize, limit-len(b)))
+	}
+}
+
+// Scan returns the next token found by split, which is called with the
+// buffered bytes after the cursor exactly as bufio.Scanner calls it, so any
+// bufio.SplitFunc can be used. The cursor is advanced as split directs, and
+// the token is a copy that can be pushed back, with whatever split skipped,
+// by UnreadToken. Scan returns io.EOF once no tokens remain, ErrBufferFull
+// if a token doesn't fit in a buffer that can't grow and ErrTokenTooLong
+// if it's longer than the maximum token size.
+func (u *Unreader) Scan(split bufio.SplitFunc) ([]byte, error) {
+	u.clearLast()
+	atEOF := u.err == io.EOF
+	size := 0
+	for {
+		data := u.replay()
+		advance, token, err := split(data, atEOF)
+		final := err == bufio.ErrFinalToken
+		if err != nil && !final {
+			return nil, err
+		}
+		if advance < 0 {
+			return nil, bufio.ErrNegativeAdvance
+		}
+		if advance > len(data) {
+			return nil, bufio.ErrAdvanceTooFar
+		}
+		if u.maxToken > 0 && len(token) > u.maxToken {
+			return nil, ErrTokenTooLong
+		}
+		u.cursor += int64(advance)
+		size += advance
+		if final {
+			// no tokens are to follow
+			u.err = io.EOF
+			if token == nil {
+				return nil, io.EOF
+			}
+		}
+		if token != nil {
+			u.lastTokenSize = size
+			return append([]byte(nil), token...), nil
+		}
+		if advance > 0 {
+			continue
+		}
+
+		if atEOF {
+			return nil, io.EOF
+		}
+		if limit, tooLong := u.tokenLimit(); len(data) >= limit {
+			return nil, tooLong
+		}
+		if int64(len(data)) >= u.cb.Size() {
+			u.grow(2 * u.cb.Size())
+		}
+		if _, err := u.fill(min(fillSize, int(u.cb.Size())-len(data))); err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			u.err = err
+			atEOF = true
+		}
+	}
+}
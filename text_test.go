@@ -0,0 +1,281 @@
+package unreader
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		in      string
+		pattern string
+		limit   int
+		want    int
+		err     error
+	}{
+		{"hello\r\n\r\nbody", "\r\n\r\n", 64, 5, nil},
+		{"hello\r\n\r\nbody", "\r\n\r\n", 8, -1, nil},
+		{"hello\r\n\r\nbody", "\r\n\r\n", 9, 5, nil},
+		{"hello", "x", 64, -1, io.EOF},
+		{"hello", "h", 0, -1, nil},
+		{"hello", "h", -1, -1, ErrNegativeCount},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		got, err := u.Index([]byte(tt.pattern), tt.limit)
+		if got != tt.want || err != tt.err {
+			t.Errorf("Index(%q, %q, %d) = %d, %v; want %d, %v", tt.in, tt.pattern, tt.limit, got, err, tt.want, tt.err)
+		}
+		if u.Cursor() != 0 {
+			t.Errorf("Index(%q, %q, %d) consumed %d bytes", tt.in, tt.pattern, tt.limit, u.Cursor())
+		}
+	}
+}
+
+// dataErrReader returns err once, along with the last of data, and then
+// io.EOF.
+type dataErrReader struct {
+	data string
+	err  error
+}
+
+func (r *dataErrReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	if r.data != "" {
+		return n, nil
+	}
+	err := r.err
+	r.err = io.EOF
+	return n, err
+}
+
+func TestReadUntilKeepsError(t *testing.T) {
+	errBroken := errors.New("connection broken")
+	u, _ := NewUnreader(16, &dataErrReader{"ab\r\ncd", errBroken})
+	b, err := u.ReadUntil([]byte("\r\n"), true)
+	if string(b) != "ab" || err != nil {
+		t.Fatalf("ReadUntil = %q, %v", b, err)
+	}
+	b, err = u.ReadUntil([]byte("\r\n"), true)
+	if string(b) != "cd" || err != errBroken {
+		t.Fatalf("ReadUntil after delim = %q, %v; want %q, %v", b, err, "cd", errBroken)
+	}
+}
+
+func TestScanFinalToken(t *testing.T) {
+	split := func(data []byte, atEOF bool) (int, []byte, error) {
+		if len(data) > 0 && data[0] == '.' {
+			return 1, nil, bufio.ErrFinalToken
+		}
+		return bufio.ScanWords(data, atEOF)
+	}
+	u := NewUnreaderString("a b .c")
+	for _, want := range []string{"a", "b"} {
+		tok, err := u.Scan(split)
+		if err != nil || string(tok) != want {
+			t.Fatalf("Scan() = %q, %v, want %q", tok, err, want)
+		}
+	}
+	if tok, err := u.Scan(split); err != io.EOF {
+		t.Fatalf("Scan() = %q, %v, want io.EOF", tok, err)
+	}
+	if err := u.UnreadToken(); err != ErrInvalidUnreadToken {
+		t.Errorf("UnreadToken() = %v, want ErrInvalidUnreadToken", err)
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		lines []string
+	}{
+		{"lf", "a\nbc\n", []string{"a", "bc"}},
+		{"crlf", "a\r\nbc\r\n", []string{"a", "bc"}},
+		{"no final newline", "a\nbc", []string{"a", "bc"}},
+		{"empty lines", "\n\nx\n", []string{"", "", "x"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		for _, want := range tt.lines {
+			line, err := u.ReadLine()
+			if string(line) != want || err != nil {
+				t.Fatalf("%s: ReadLine() = %q, %v, want %q", tt.name, line, err, want)
+			}
+		}
+		if line, err := u.ReadLine(); err != io.EOF {
+			t.Errorf("%s: ReadLine() at end = %q, %v, want io.EOF", tt.name, line, err)
+		}
+	}
+}
+
+func TestUnreadLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		before func(u *Unreader)
+		err    error
+		rest   string
+	}{
+		{"after ReadLine", func(u *Unreader) { u.ReadLine() }, nil, "one\r\ntwo"},
+		{"after second", func(u *Unreader) { u.ReadLine(); u.ReadLine() }, nil, "two"},
+		{"twice", func(u *Unreader) { u.ReadLine(); u.UnreadLine() }, ErrInvalidUnreadLine, "one\r\ntwo"},
+		{"after ReadByte", func(u *Unreader) { u.ReadLine(); u.ReadByte() }, ErrInvalidUnreadLine, "wo"},
+		{"after EOF", func(u *Unreader) { u.ReadLine(); u.ReadLine(); u.ReadLine() }, ErrInvalidUnreadLine, ""},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader("one\r\ntwo"))
+		tt.before(u)
+		if err := u.UnreadLine(); err != tt.err {
+			t.Errorf("%s: UnreadLine() = %v, want %v", tt.name, err, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestReadBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string // tokens before the error
+		last string   // returned with err
+		err  error
+	}{
+		{"delimited", "a,bc,", []string{"a,", "bc,"}, "", io.EOF},
+		{"unterminated", "a,bc", []string{"a,"}, "bc", io.EOF},
+		{"longer than the buffer", strings.Repeat("x", 40) + ",", []string{strings.Repeat("x", 40) + ","}, "", io.EOF},
+		{"empty", "", nil, "", io.EOF},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		for _, want := range tt.want {
+			if tok, err := u.ReadBytes(','); string(tok) != want || err != nil {
+				t.Fatalf("%s: ReadBytes() = %q, %v, want %q", tt.name, tok, err, want)
+			}
+		}
+		if s, err := u.ReadString(','); s != tt.last || err != tt.err {
+			t.Errorf("%s: ReadString() = %q, %v, want %q, %v", tt.name, s, err, tt.last, tt.err)
+		}
+	}
+
+	// a token is pushed back as a whole
+	u, _ := NewUnreader(16, strings.NewReader("key=value;rest"))
+	u.ReadString('=')
+	u.ReadBytes(';')
+	if err := u.UnreadToken(); err != nil {
+		t.Fatalf("UnreadToken() = %v", err)
+	}
+	if err := u.UnreadToken(); err != ErrInvalidUnreadToken {
+		t.Fatalf("second UnreadToken() = %v, want ErrInvalidUnreadToken", err)
+	}
+	if rest, _ := io.ReadAll(u); string(rest) != "value;rest" {
+		t.Fatalf("left %q after UnreadToken", rest)
+	}
+}
+
+func TestReadSlice(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		growable bool
+		want     string
+		err      error
+	}{
+		{"delimited", "ab,cd", false, "ab,", nil},
+		{"unterminated", "abcd", false, "abcd", io.EOF},
+		{"fills the buffer", "abcdefghij,", false, "abcdefgh", ErrBufferFull},
+		{"growable", "abcdefghij,", true, "abcdefghij,", nil},
+	}
+	for _, tt := range tests {
+		u, _ := New(strings.NewReader(tt.in), WithBufferSize(8), WithGrowable(tt.growable))
+		line, err := u.ReadSlice(',')
+		if string(line) != tt.want || err != tt.err {
+			t.Errorf("%s: ReadSlice() = %q, %v, want %q, %v", tt.name, line, err, tt.want, tt.err)
+		}
+		if u.Cursor() != int64(len(tt.want)) {
+			t.Errorf("%s: cursor at %d after ReadSlice", tt.name, u.Cursor())
+		}
+	}
+
+	// the slice aliases the buffer, and can be pushed back
+	u := NewUnreaderString("ab,cd")
+	line, _ := u.ReadSlice(',')
+	if err := u.UnreadToken(); err != nil {
+		t.Fatalf("UnreadToken() after ReadSlice = %v", err)
+	}
+	if &line[0] != &u.PeekAvailable()[0] {
+		t.Errorf("ReadSlice returned a copy of the buffer")
+	}
+	if rest, _ := io.ReadAll(u); string(rest) != "ab,cd" {
+		t.Fatalf("left %q after UnreadToken", rest)
+	}
+}
+
+func TestReadToken(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  error
+		rest string // after ReadToken
+	}{
+		{"first word", "USER alice\r\n", "USER", nil, " alice\r\n"},
+		{"leading space", "  \tQUIT\r\n", "QUIT", nil, "\r\n"},
+		{"last word", "NOOP", "NOOP", nil, ""},
+		{"only space", " \r\n", "", io.EOF, " \r\n"},
+		{"empty", "", "", io.EOF, ""},
+		{"past the buffer", strings.Repeat("x", 20) + " y", "", ErrBufferFull, strings.Repeat("x", 20) + " y"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader(tt.in))
+		if w, err := u.PeekToken(); string(w) != tt.want || err != tt.err || u.Cursor() != 0 {
+			t.Errorf("%s: PeekToken() = %q, %v, cursor %d, want %q, %v", tt.name, w, err, u.Cursor(), tt.want, tt.err)
+		}
+		if w, err := u.ReadToken(); string(w) != tt.want || err != tt.err {
+			t.Errorf("%s: ReadToken() = %q, %v, want %q, %v", tt.name, w, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+
+	// the word and the space before it are pushed back together
+	u := NewUnreaderString(" GET /")
+	u.ReadToken()
+	if err := u.UnreadToken(); err != nil || u.Cursor() != 0 {
+		t.Fatalf("UnreadToken() after ReadToken = %v, cursor %d", err, u.Cursor())
+	}
+}
+
+func TestPeekLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		growable bool
+		want     string
+		err      error
+	}{
+		{"lf", "one\ntwo", false, "one", nil},
+		{"crlf", "one\r\ntwo", false, "one", nil},
+		{"final line", "one", false, "one", nil},
+		{"empty", "", false, "", io.EOF},
+		{"fills the buffer", "0123456789\n", false, "01234567", ErrBufferFull},
+		{"growable", "0123456789\n", true, "0123456789", nil},
+	}
+	for _, tt := range tests {
+		u, _ := New(strings.NewReader(tt.in), WithBufferSize(8), WithGrowable(tt.growable))
+		line, err := u.PeekLine()
+		if string(line) != tt.want || err != tt.err {
+			t.Errorf("%s: PeekLine() = %q, %v, want %q, %v", tt.name, line, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.in {
+			t.Errorf("%s: PeekLine consumed bytes, left %q", tt.name, rest)
+		}
+	}
+}
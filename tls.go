@@ -0,0 +1,175 @@
+package unreader
+
+// recordTypeHandshake and handshakeTypeClientHello identify a TLS
+// handshake record and the ClientHello message within it.
+const (
+	recordTypeHandshake      = 0x16
+	handshakeTypeClientHello = 0x01
+)
+
+// maxClientHello bounds how many bytes of handshake PeekClientHello
+// reassembles across records.
+const maxClientHello = 1 << 16
+
+// ClientHello is what PeekClientHello found in a TLS ClientHello.
+type ClientHello struct {
+	Version    uint16   // legacy_version field, such as 0x0303 for TLS 1.2 and 1.3
+	ServerName string   // from the server_name extension, or ""
+	ALPN       []string // protocols from the ALPN extension, in order
+}
+
+// IsTLS reports whether the stream after the cursor starts with a TLS
+// handshake record, without consuming anything.
+func (u *Unreader) IsTLS() bool {
+	b := u.peekQuiet(3)
+	return len(b) == 3 && b[0] == recordTypeHandshake && b[1] == 3 && b[2] <= 4
+}
+
+// PeekClientHello parses the TLS ClientHello at the cursor without
+// consuming it, so the connection can still be handed, bytes and all, to
+// crypto/tls. The hello may span several records, all of which must fit in
+// the buffer. It returns ErrNoMatch if the stream doesn't start with a TLS
+// handshake, ErrMalformed if the hello doesn't parse and an
+// *IncompleteError if it's cut short.
+func (u *Unreader) PeekClientHello() (*ClientHello, error) {
+	if !u.IsTLS() {
+		if _, err := u.Peek(3); err != nil {
+			return nil, incomplete(3, u.replay(), err)
+		}
+		return nil, ErrNoMatch
+	}
+	var hs []byte
+	for off := 0; ; {
+		h, err := u.Peek(off + 5)
+		if len(h) < off+5 {
+			return nil, incomplete(off+5, h, err)
+		}
+		if h[off] != recordTypeHandshake {
+			return nil, ErrMalformed
+		}
+		size := off + 5 + (int(h[off+3])<<8 | int(h[off+4]))
+		rec, err := u.Peek(size)
+		if len(rec) < size {
+			return nil, incomplete(size, rec, err)
+		}
+		hs = append(hs, rec[off+5:]...)
+		off = size
+		if len(hs) >= 4 {
+			n := 4 + (int(hs[1])<<16 | int(hs[2])<<8 | int(hs[3]))
+			if n > maxClientHello {
+				return nil, ErrTokenTooLong
+			}
+			if len(hs) >= n {
+				hs = hs[:n]
+				break
+			}
+		}
+	}
+	return parseClientHello(hs)
+}
+
+// parseClientHello parses a handshake message holding a ClientHello.
+func parseClientHello(b []byte) (*ClientHello, error) {
+	p := wire(b)
+	typ, _ := p.u8()
+	body, ok := p.vec(3)
+	if typ != handshakeTypeClientHello || !ok {
+		return nil, ErrMalformed
+	}
+	p = body
+	var h ClientHello
+	if h.Version, ok = p.u16(); !ok {
+		return nil, ErrMalformed
+	}
+	_, ok1 := p.next(32) // random
+	_, ok2 := p.vec(1)   // session ID
+	_, ok3 := p.vec(2)   // cipher suites
+	_, ok4 := p.vec(1)   // compression methods
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, ErrMalformed
+	}
+	if len(p) == 0 {
+		return &h, nil
+	}
+	exts, ok := p.vec(2)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	for len(exts) > 0 {
+		typ, ok1 := exts.u16()
+		data, ok2 := exts.vec(2)
+		if !ok1 || !ok2 {
+			return nil, ErrMalformed
+		}
+		switch typ {
+		case 0: // server_name
+			list, ok := data.vec(2)
+			for ok && len(list) > 0 {
+				var kind uint8
+				var name wire
+				kind, ok = list.u8()
+				if name, ok = list.vec(2); ok && kind == 0 {
+					h.ServerName = string(name)
+					break
+				}
+			}
+			if !ok {
+				return nil, ErrMalformed
+			}
+		case 16: // application_layer_protocol_negotiation
+			list, ok := data.vec(2)
+			for ok && len(list) > 0 {
+				var proto wire
+				if proto, ok = list.vec(1); ok {
+					h.ALPN = append(h.ALPN, string(proto))
+				}
+			}
+			if !ok {
+				return nil, ErrMalformed
+			}
+		}
+	}
+	return &h, nil
+}
+
+// wire is a cursor over big-endian, length-prefixed protocol fields.
+type wire []byte
+
+// next takes the next n bytes.
+func (w *wire) next(n int) (wire, bool) {
+	if len(*w) < n {
+		return nil, false
+	}
+	b := (*w)[:n]
+	*w = (*w)[n:]
+	return b, true
+}
+
+// uint takes an n-byte unsigned integer.
+func (w *wire) uint(n int) (int, bool) {
+	b, ok := w.next(n)
+	v := 0
+	for _, c := range b {
+		v = v<<8 | int(c)
+	}
+	return v, ok
+}
+
+func (w *wire) u8() (uint8, bool) {
+	v, ok := w.uint(1)
+	return uint8(v), ok
+}
+
+func (w *wire) u16() (uint16, bool) {
+	v, ok := w.uint(2)
+	return uint16(v), ok
+}
+
+// vec takes a field prefixed by an n-byte length.
+func (w *wire) vec(n int) (wire, bool) {
+	size, ok := w.uint(n)
+	if !ok {
+		return nil, false
+	}
+	return w.next(size)
+}
@@ -0,0 +1,106 @@
+package unreader
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// clientHello returns a ClientHello handshake message with the given server
+// name and ALPN protocols.
+func clientHello(name string, alpn ...string) []byte {
+	vec := func(n int, b []byte) []byte {
+		var h []byte
+		for i := n - 1; i >= 0; i-- {
+			h = append(h, byte(len(b)>>(8*i)))
+		}
+		return append(h, b...)
+	}
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...)        // random
+	body = append(body, 0)                          // session ID
+	body = append(body, vec(2, []byte{0x13, 1})...) // cipher suites
+	body = append(body, vec(1, []byte{0})...)       // compression methods
+	var exts []byte
+	if name != "" {
+		sni := vec(2, append([]byte{0}, vec(2, []byte(name))...))
+		exts = append(exts, 0, 0)
+		exts = append(exts, vec(2, sni)...)
+	}
+	if len(alpn) > 0 {
+		var list []byte
+		for _, p := range alpn {
+			list = append(list, vec(1, []byte(p))...)
+		}
+		exts = append(exts, 0, 16)
+		exts = append(exts, vec(2, vec(2, list))...)
+	}
+	body = append(body, vec(2, exts)...)
+	return append([]byte{handshakeTypeClientHello}, vec(3, body)...)
+}
+
+// tlsRecords splits a handshake message into records of at most n bytes.
+func tlsRecords(hs []byte, n int) string {
+	var b []byte
+	for len(hs) > 0 {
+		m := min(n, len(hs))
+		b = append(b, recordTypeHandshake, 3, 1, byte(m>>8), byte(m))
+		b = append(b, hs[:m]...)
+		hs = hs[m:]
+	}
+	return string(b)
+}
+
+func TestPeekClientHello(t *testing.T) {
+	hello := clientHello("example.com", "h2", "http/1.1")
+	tests := []struct {
+		name string
+		in   string
+		sni  string
+		alpn int
+		err  error
+	}{
+		{"one record", tlsRecords(hello, 1<<14), "example.com", 2, nil},
+		{"split records", tlsRecords(hello, 7), "example.com", 2, nil},
+		{"no extensions", tlsRecords(clientHello(""), 1<<14), "", 0, nil},
+		{"truncated", tlsRecords(hello, 1<<14)[:40], "", 0, io.ErrUnexpectedEOF},
+		{"not tls", "GET / HTTP/1.1\r\n", "", 0, ErrNoMatch},
+		{"short body", tlsRecords([]byte{1, 0, 0, 2, 3, 3}, 16), "", 0, ErrMalformed},
+		{"not a hello", tlsRecords([]byte{2, 0, 0, 0}, 16), "", 0, ErrMalformed},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(1024, strings.NewReader(tt.in))
+		h, err := u.PeekClientHello()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: PeekClientHello() error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if u.Cursor() != 0 {
+			t.Errorf("%s: PeekClientHello() moved the cursor to %d", tt.name, u.Cursor())
+		}
+		if err == nil && (h.ServerName != tt.sni || len(h.ALPN) != tt.alpn || h.Version != 0x0303) {
+			t.Errorf("%s: PeekClientHello() = %+v", tt.name, h)
+		}
+	}
+}
+
+func TestPeekClientHelloCryptoTLS(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	go func() {
+		tc := tls.Client(c1, &tls.Config{ServerName: "example.com", NextProtos: []string{"h2", "http/1.1"}, InsecureSkipVerify: true})
+		tc.Handshake()
+	}()
+	u, _ := NewUnreader(1<<16, c2)
+	if !u.IsTLS() {
+		t.Fatal("IsTLS() = false for a crypto/tls client")
+	}
+	h, err := u.PeekClientHello()
+	if err != nil || h.ServerName != "example.com" || len(h.ALPN) != 2 || h.ALPN[0] != "h2" {
+		t.Fatalf("PeekClientHello() = %+v, %v", h, err)
+	}
+}
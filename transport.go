@@ -0,0 +1,73 @@
+package unreader
+
+import "net/http"
+
+// ReplayTransport is an http.RoundTripper that makes request bodies
+// replayable with RewindableBody, so that requests can be retried after a
+// connection error without the caller buffering the body. Setting GetBody
+// also lets Transport use its own retries for requests with a body.
+type ReplayTransport struct {
+	// Transport sends the requests. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// BodySize is how many bytes of each request body are kept for replay.
+	// If zero, the default buffer size is used. A request whose body was
+	// read past BodySize before failing isn't retried.
+	BodySize int64
+
+	// Retries is how many more times a replayable request is sent after
+	// Transport returns an error. Requests are replayable if their method
+	// is idempotent or they carry an Idempotency-Key header.
+	Retries int
+}
+
+// RoundTrip implements http.RoundTripper. The request is cloned, so the
+// caller's request isn't modified.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	size := t.BodySize
+	if size == 0 {
+		size = defaultBufferSize
+	}
+	r := req.Clone(req.Context())
+	if _, err := RewindableBody(r, size); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	b, _ := r.Body.(*rewindBody)
+	if b != nil {
+		defer b.release()
+	}
+	for i := 0; ; i++ {
+		resp, err := rt.RoundTrip(r)
+		if err == nil || i >= t.Retries || !replayable(r) || r.Context().Err() != nil {
+			return resp, err
+		}
+		if b != nil {
+			body, gerr := r.GetBody()
+			if gerr != nil {
+				return resp, err
+			}
+			r.Body = body
+		}
+	}
+}
+
+// replayable reports whether r can be sent again after a failure without
+// the server acting on it twice, as Transport decides for its own retries.
+func replayable(r *http.Request) bool {
+	switch r.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := r.Header["Idempotency-Key"]
+	if !ok {
+		_, ok = r.Header["X-Idempotency-Key"]
+	}
+	return ok
+}
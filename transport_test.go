@@ -0,0 +1,78 @@
+package unreader
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// flakyTransport fails the first fails requests after reading part of
+// their body, then records the bodies of the rest.
+type flakyTransport struct {
+	fails  int
+	bodies []string
+}
+
+func (t *flakyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	defer r.Body.Close()
+	if t.fails > 0 {
+		t.fails--
+		r.Body.Read(make([]byte, 3))
+		return nil, errors.New("connection reset")
+	}
+	b, _ := io.ReadAll(r.Body)
+	t.bodies = append(t.bodies, string(b))
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+// closeReader records whether it was closed.
+type closeReader struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestReplayTransport(t *testing.T) {
+	tests := []struct {
+		method  string
+		fails   int
+		retries int
+		bodies  int
+	}{
+		{http.MethodPut, 2, 2, 1},
+		{http.MethodPut, 3, 2, 0},
+		{http.MethodPost, 1, 2, 0},
+		{http.MethodGet, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		ft := &flakyTransport{fails: tt.fails}
+		rt := &ReplayTransport{Transport: ft, BodySize: 64, Retries: tt.retries}
+		body := &closeReader{Reader: strings.NewReader("payload")}
+		req, _ := http.NewRequest(tt.method, "http://example.com/", body)
+		resp, err := rt.RoundTrip(req)
+		if (err == nil) != (tt.bodies > 0) || len(ft.bodies) != tt.bodies {
+			t.Errorf("%s with %d failures: err %v, %d bodies sent", tt.method, tt.fails, err, len(ft.bodies))
+			continue
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		for _, b := range ft.bodies {
+			if b != "payload" {
+				t.Errorf("%s with %d failures: body %q", tt.method, tt.fails, b)
+			}
+		}
+		if !body.closed {
+			t.Errorf("%s with %d failures: body not closed", tt.method, tt.fails)
+		}
+		if req.Body != body {
+			t.Errorf("%s with %d failures: caller's request modified", tt.method, tt.fails)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package unreader
+
+// Begin starts a speculative region at the cursor. Each Begin must be
+// matched by a Commit or Rollback. Regions nest: committing an inner region
+// leaves the outer one able to roll back over it. Like pushed marks, open
+// regions pin their bytes in the buffer.
+func (u *Unreader) Begin() {
+	u.txns = append(u.txns, u.Mark())
+}
+
+// Commit ends the innermost speculative region, keeping the cursor where it
+// is. It returns ErrNoTransaction if no region is open.
+func (u *Unreader) Commit() error {
+	if len(u.txns) == 0 {
+		return ErrNoTransaction
+	}
+	u.txns = u.txns[:len(u.txns)-1]
+	return nil
+}
+
+// Rollback ends the innermost speculative region and restores the cursor to
+// where it began. The region is ended even if the rewind fails because its
+// bytes were evicted. It returns ErrNoTransaction if no region is open.
+func (u *Unreader) Rollback() error {
+	if len(u.txns) == 0 {
+		return ErrNoTransaction
+	}
+	m := u.txns[len(u.txns)-1]
+	u.txns = u.txns[:len(u.txns)-1]
+	return u.Rewind(m)
+}
+
+// InTransaction reports how many speculative regions are open.
+func (u *Unreader) InTransaction() int {
+	return len(u.txns)
+}
+
+// Try runs f inside a speculative region. If f returns an error, the cursor
+// is restored to where it was when Try was called and the error is returned;
+// otherwise the region is committed. f can return ErrBacktrack to roll back
+// when the input simply doesn't match. Regions f leaves open are closed,
+// and f may also close Try's own region. If f panics, the cursor is
+// restored before the panic continues.
+func (u *Unreader) Try(f func(*Unreader) error) (err error) {
+	depth := len(u.txns)
+	u.Begin()
+	m := u.txns[depth]
+	returned := false
+	defer func() {
+		if len(u.txns) > depth {
+			u.txns = u.txns[:depth]
+		}
+		if returned && err == nil {
+			return
+		}
+		if rerr := u.Rewind(m); rerr != nil && returned {
+			err = rerr
+		}
+	}()
+	err = f(u)
+	returned = true
+	return err
+}
@@ -0,0 +1,149 @@
+package unreader
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTransactions(t *testing.T) {
+	tests := []struct {
+		name   string
+		ops    func(u *Unreader) error
+		err    error
+		cursor int64
+		open   int
+	}{
+		{"commit", func(u *Unreader) error {
+			u.Begin()
+			u.Discard(3)
+			return u.Commit()
+		}, nil, 3, 0},
+		{"rollback", func(u *Unreader) error {
+			u.Begin()
+			u.Discard(3)
+			return u.Rollback()
+		}, nil, 0, 0},
+		{"inner commit, outer rollback", func(u *Unreader) error {
+			u.Begin()
+			u.Discard(1)
+			u.Begin()
+			u.Discard(2)
+			u.Commit()
+			return u.Rollback()
+		}, nil, 0, 0},
+		{"inner rollback, outer commit", func(u *Unreader) error {
+			u.Begin()
+			u.Discard(1)
+			u.Begin()
+			u.Discard(2)
+			u.Rollback()
+			return u.Commit()
+		}, nil, 1, 0},
+		{"still open", func(u *Unreader) error {
+			u.Begin()
+			u.Begin()
+			u.Discard(2)
+			return u.Rollback()
+		}, nil, 0, 1},
+		{"commit with none open", func(u *Unreader) error { return u.Commit() }, ErrNoTransaction, 0, 0},
+		{"rollback with none open", func(u *Unreader) error { return u.Rollback() }, ErrNoTransaction, 0, 0},
+	}
+	for _, tt := range tests {
+		u := NewUnreaderString("0123456789")
+		if err := tt.ops(u); err != tt.err || u.Cursor() != tt.cursor {
+			t.Errorf("%s: err %v, cursor %d; want %v, %d", tt.name, err, u.Cursor(), tt.err, tt.cursor)
+		}
+		if n := u.InTransaction(); n != tt.open {
+			t.Errorf("%s: %d regions open, want %d", tt.name, n, tt.open)
+		}
+	}
+}
+
+func TestTry(t *testing.T) {
+	tests := []struct {
+		name   string
+		f      func(u *Unreader) error
+		err    error
+		cursor int64
+	}{
+		{"commit", func(u *Unreader) error {
+			u.Discard(3)
+			return nil
+		}, nil, 3},
+		{"backtrack", func(u *Unreader) error {
+			u.Discard(3)
+			return ErrBacktrack
+		}, ErrBacktrack, 0},
+		{"nested", func(u *Unreader) error {
+			u.Discard(1)
+			u.Try(func(u *Unreader) error {
+				u.Discard(2)
+				return ErrBacktrack
+			})
+			u.Begin()
+			u.Discard(2)
+			return nil
+		}, nil, 3},
+		{"closes own region", func(u *Unreader) error {
+			u.Discard(2)
+			u.Commit()
+			return ErrBacktrack
+		}, ErrBacktrack, 0},
+		{"rolls back own region", func(u *Unreader) error {
+			u.Discard(2)
+			u.Rollback()
+			u.Discard(4)
+			return nil
+		}, nil, 4},
+	}
+	for _, tt := range tests {
+		u := NewUnreaderString("0123456789")
+		u.Begin()
+		err := u.Try(tt.f)
+		if err != tt.err || u.Cursor() != tt.cursor {
+			t.Errorf("%s: Try = %v, cursor %d; want %v, %d", tt.name, err, u.Cursor(), tt.err, tt.cursor)
+		}
+		if n := u.InTransaction(); n != 1 {
+			t.Errorf("%s: %d regions open after Try, want 1", tt.name, n)
+		}
+	}
+}
+
+func TestTryReset(t *testing.T) {
+	u := NewUnreaderString("0123456789")
+	err := u.Try(func(u *Unreader) error {
+		u.Reset(strings.NewReader("abc"))
+		return nil
+	})
+	if err != nil || u.InTransaction() != 0 {
+		t.Fatalf("Try = %v, %d regions open", err, u.InTransaction())
+	}
+	u.Discard(2)
+	err = u.Try(func(u *Unreader) error {
+		u.Reset(strings.NewReader("xyz"))
+		return ErrBacktrack
+	})
+	if err == nil || u.InTransaction() != 0 {
+		t.Fatalf("Try = %v, %d regions open", err, u.InTransaction())
+	}
+}
+
+func TestTryPanic(t *testing.T) {
+	u := NewUnreaderString("0123456789")
+	errBoom := errors.New("boom")
+	func() {
+		defer func() {
+			if r := recover(); r != errBoom {
+				t.Fatalf("recovered %v", r)
+			}
+		}()
+		u.Try(func(u *Unreader) error {
+			u.Discard(5)
+			panic(errBoom)
+		})
+	}()
+	if u.Cursor() != 0 || u.InTransaction() != 0 {
+		t.Fatalf("after panic: cursor %d, %d regions open", u.Cursor(), u.InTransaction())
+	}
+}
@@ -6,104 +6,841 @@
 package unreader
 
 import (
-	"fmt"
 	"io"
-	"unicode/utf8"
+	"strings"
+	"time"
 
 	"github.com/freb/circbuf"
+	"golang.org/x/text/transform"
 )
 
-type unreader struct {
+// Unreader wraps an io.Reader and records the bytes read from it in a
+// circular buffer so that they can be unread and read again.
+type Unreader struct {
+	*stream
+	cursor int64 // byte to read next. if cursor < bytesRead, read from buffer
+
+	lastRuneSize  int // size of the rune returned by the last ReadRune, or 0
+	lastTokenSize int // size of the token returned by the last ReadLine or ReadBytes, or 0
+
+	greedy      bool // continue a replaying Read with the underlying reader
+	maxToken    int  // longest token delimiter-seeking reads return, or 0
+	strictRunes bool // fail on invalid runes instead of decoding RuneError
+
+	marks []namedMark // stack of pushed marks
+	txns  []Mark      // starts of open transactions
+
+	lexStart int64 // start of the lexer's pending token
+}
+
+// stream is the state of an Unreader that its forks share: the buffer, the
+// underlying reader and the bookkeeping of bytes read from it.
+type stream struct {
 	cb        *circbuf.Buffer
-	rd        io.Reader // reader provided by the client
+	view      []byte    // cached cb.Bytes(), nil after the buffer changes
+	rd        io.Reader // reader provided by the client, wrapped as options require
+	src       io.Reader // reader provided by the client
 	bytesRead int64     // read from underlying reader
-	cursor    int64     // byte to read next. if cursor < bytesRead, read from buffer
+	written   int64     // recorded in the buffer over its lifetime
+
+	fillBuf []byte // scratch space for reads that don't move the cursor
+	err     error  // error from the underlying reader not yet returned
 
-	runeBuf []byte
-	runePos int
+	recording bool // record bytes returned by Read from the underlying reader
+	growable  bool // grow the buffer instead of failing when it's too small
+
+	stripBOM   bool                    // strip a byte order mark from each attached reader
+	bom        *bomReader              // strips the mark from the attached reader
+	transforms []transform.Transformer // applied to each attached reader
+	runeEnc    Encoding                // encoding for ReadRune, or EncodingUnknown to detect
+
+	lines     int64   // newlines read from underlying reader
+	nl        []int64 // offsets of newlines still in the buffer
+	lineStart int64   // start of the first line not tracked by nl
+	runes     int64   // runes read from underlying reader
+
+	deadline time.Time // read deadline set with SetReadDeadline, restored after timed reads
+	tee      io.Writer // receives each byte read from the underlying reader, or nil
+
+	readers []*Unreader // Unreaders sharing the stream, which pin each other's cursors
 }
 
-func (u *unreader) Bytes() []byte {
+// maxConsecutiveEmptyReads bounds how many times fill retries an underlying
+// reader that returns neither data nor an error.
+const maxConsecutiveEmptyReads = 100
+
+// fillSize is how much ReadByte and similar small reads request from the
+// underlying reader when there is nothing left to replay.
+const fillSize = 4096
+
+func (u *Unreader) Bytes() []byte {
 	return u.cb.Bytes()
 }
 
-func (u *unreader) BytesRead() int64 {
+func (u *Unreader) BytesRead() int64 {
 	return u.bytesRead
 }
 
-func (u *unreader) Cursor() int64 {
+func (u *Unreader) Cursor() int64 {
 	return u.cursor
 }
 
+// Size returns the size of the buffer, which bounds how far the cursor can
+// be rewound.
+func (u *Unreader) Size() int64 {
+	return u.cb.Size()
+}
+
+// Len returns the number of bytes currently held in the buffer, without
+// copying them like Bytes.
+func (u *Unreader) Len() int64 {
+	return u.retained()
+}
+
+// TotalWritten returns the number of bytes ever recorded in the buffer. It
+// differs from BytesRead when recording has been off.
+func (u *Unreader) TotalWritten() int64 {
+	return u.written
+}
+
+// Buffered returns the number of unread bytes between the cursor and
+// BytesRead. Reads are served from these bytes before the underlying reader
+// is used again, so a Read will not block while Buffered is non-zero.
+func (u *Unreader) Buffered() int64 {
+	return u.bytesRead - u.cursor
+}
+
 // NewUnreader returns an initialized Unreader
-func NewUnreader(size int64, r io.Reader) (*unreader, error) {
-	cb, err := circbuf.NewBuffer(size)
+func NewUnreader(size int64, r io.Reader) (*Unreader, error) {
+	return New(r, WithBufferSize(size))
+}
+
+// New returns an Unreader reading from r, configured by opts.
+func New(r io.Reader, opts ...Option) (*Unreader, error) {
+	o := options{
+		size:      defaultBufferSize,
+		recording: true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if int64(len(o.prefill)) > o.size {
+		if !o.growable {
+			return nil, ErrBufferFull
+		}
+		o.size = int64(len(o.prefill))
+	}
+
+	if o.normalizeNewlines {
+		o.transforms = append(o.transforms, newlineNormalizer{})
+	}
+
+	cb, err := circbuf.NewBuffer(o.size)
 	if err != nil {
 		return nil, err
 	}
-	ur := &unreader{
-		cb:      cb,
-		rd:      r,
-		runeBuf: make([]byte, utf8.MaxRune),
+	ur := &Unreader{
+		stream: &stream{
+			cb:         cb,
+			recording:  o.recording,
+			growable:   o.growable,
+			stripBOM:   o.stripBOM,
+			transforms: o.transforms,
+			runeEnc:    o.runeEnc,
+		},
+		greedy:      o.greedy,
+		maxToken:    o.maxTokenSize,
+		strictRunes: o.strictRunes,
 	}
+	ur.readers = []*Unreader{ur}
+	ur.attach(r)
+	ur.record(o.prefill)
+	ur.tee = o.tee
 	return ur, nil
 }
 
-func (u *unreader) Unread(c int64) error {
+// NewUnreaderBytes returns an Unreader that reads b. All of b is placed in
+// the buffer up front, so any of it can be unread at any point.
+func NewUnreaderBytes(b []byte) *Unreader {
+	// the size always fits the prefill, so this can't fail
+	u, _ := New(strings.NewReader(""), WithBufferSize(max(int64(len(b)), 1)), WithPrefill(b))
+	// nothing follows b, so end-of-stream checks needn't read to find out
+	u.err = io.EOF
+	return u
+}
+
+// NewUnreaderString is like NewUnreaderBytes but reads s.
+func NewUnreaderString(s string) *Unreader {
+	return NewUnreaderBytes([]byte(s))
+}
+
+// StopRecording stops copying bytes returned by Read into the buffer, for
+// when no more rewinding is needed. Bytes already buffered can still be
+// replayed, and Peek keeps working, but bytes read straight from the
+// underlying reader after this can't be unread.
+func (u *Unreader) StopRecording() {
+	u.recording = false
+}
+
+// StartRecording resumes recording bytes returned by Read. Only bytes read
+// after this call can be unread.
+func (u *Unreader) StartRecording() {
+	u.recording = true
+}
+
+// Reset discards all buffered bytes and counters and attaches r as the new
+// underlying reader, keeping the allocated buffer for reuse. An Unreader
+// with forks is detached from them first, and gets a new buffer instead.
+func (u *Unreader) Reset(r io.Reader) {
+	if len(u.readers) > 1 {
+		u.detach()
+	}
+	u.StopReadahead()
+	u.cb.Reset()
+	u.view = nil
+	u.attach(r)
+	u.bytesRead = 0
+	u.written = 0
+	u.cursor = 0
+	u.clearLast()
+	u.err = nil
+	u.marks = u.marks[:0]
+	u.txns = u.txns[:0]
+	u.lines = 0
+	u.nl = u.nl[:0]
+	u.lineStart = 0
+	u.runes = 0
+	u.lexStart = 0
+	u.deadline = time.Time{}
+}
+
+// attach sets r as the underlying reader, wrapped as the options require.
+func (u *Unreader) attach(r io.Reader) {
+	u.src = r
+	u.bom = nil
+	if u.stripBOM {
+		u.bom = &bomReader{r: r}
+		r = u.bom
+	}
+	if len(u.transforms) > 0 {
+		r = transform.NewReader(r, transform.Chain(u.transforms...))
+	}
+	u.rd = r
+}
+
+type closedReader struct{}
+
+func (closedReader) Read([]byte) (int, error) {
+	return 0, ErrClosed
+}
+
+// Close closes the underlying reader if it implements io.Closer and releases
+// the buffer. Reads after Close return ErrClosed. An Unreader with forks is
+// only detached from them, leaving the underlying reader open for them.
+func (u *Unreader) Close() error {
+	if len(u.readers) > 1 {
+		u.detach()
+	}
+	var err error
+	if c, ok := u.src.(io.Closer); ok {
+		err = c.Close()
+	}
+	u.rd = closedReader{}
+	u.src = closedReader{}
+	u.cb, _ = circbuf.NewBuffer(1)
+	u.view = nil
+	u.cursor = u.bytesRead
+	u.clearLast()
+	u.fillBuf = nil
+	u.err = nil
+	return err
+}
+
+// Unread moves the cursor back c bytes so that they are read again. If it
+// can't, it returns an *UnreadError wrapping ErrUnreadBeyondWritten if fewer
+// than c bytes have been read, or ErrUnreadBeyondBuffer if they are no longer
+// held in the buffer.
+func (u *Unreader) Unread(c int64) error {
+	if c < 0 {
+		return ErrNegativeCount
+	}
 	newCursor := u.cursor - c
-	if u.cb.TotalWritten() < (u.bytesRead - newCursor) {
-		return fmt.Errorf("cursor < total bytes written")
+	if newCursor < 0 {
+		return u.unreadError(c, ErrUnreadBeyondWritten)
 	}
-	if u.cb.Size() < (u.bytesRead - newCursor) {
-		return fmt.Errorf("cursor < buffer size")
+	if u.retained() < (u.bytesRead - newCursor) {
+		return u.unreadError(c, ErrUnreadBeyondBuffer)
 	}
 	u.cursor = newCursor
+	u.clearLast()
 	return nil
 }
 
+// UnreadAll rewinds the cursor to the earliest byte still held in the buffer
+// and returns the number of bytes unread.
+func (u *Unreader) UnreadAll() int64 {
+	c := u.MaxUnread()
+	u.cursor -= c
+	u.clearLast()
+	return c
+}
+
+// Advance moves the cursor forward n bytes within the unread bytes, without
+// copying them anywhere. It is the inverse of Unread and returns
+// ErrAdvanceBeyondBuffered if fewer than n bytes are buffered.
+func (u *Unreader) Advance(n int64) error {
+	if n < 0 {
+		return ErrNegativeCount
+	}
+	if n > u.bytesRead-u.cursor {
+		return ErrAdvanceBeyondBuffered
+	}
+	u.cursor += n
+	u.clearLast()
+	return nil
+}
+
+// clearLast forgets the last rune or token read, so that UnreadRune and
+// UnreadToken fail after any other operation.
+func (u *Unreader) clearLast() {
+	u.lastRuneSize = 0
+	u.lastTokenSize = 0
+}
+
+func (u *Unreader) unreadError(c int64, err error) error {
+	return &UnreadError{
+		Requested: c,
+		Max:       u.MaxUnread(),
+		Size:      u.cb.Size(),
+		Err:       err,
+	}
+}
+
+// MaxUnread returns how many bytes the cursor can currently be rewound: the
+// bytes before the cursor that are still held in the buffer.
+func (u *Unreader) MaxUnread() int64 {
+	return min(u.cursor, u.retained()-(u.bytesRead-u.cursor))
+}
+
 // Read functions like a standard io.Reader except if bytes have been
-// unread, it will re-read those first.
-func (u *unreader) Read(p []byte) (n int, err error) {
+// unread, it will re-read those first. A single Read returns at most the
+// buffer size, so everything returned by any one Read can be unread.
+func (u *Unreader) Read(p []byte) (n int, err error) {
 	// either return the bytes we can from the buffer, or use underlying reader
-	// for simplicity. Don't attempt to maximize bytes returned.
-	n = len(p)
-	if n == 0 {
+	// for simplicity. Don't attempt to maximize bytes returned unless greedy.
+	if len(p) == 0 {
 		return 0, nil
 	}
+	u.clearLast()
+
+	// a larger read would evict its own start from the buffer
+	if int64(len(p)) > u.cb.Size() {
+		p = p[:u.cb.Size()]
+	}
 
-	// read from reader
 	if u.cursor == u.bytesRead {
-		n, err = u.rd.Read(p)
-		u.cb.Write(p[:n])
-		u.bytesRead += int64(n)
-		u.cursor += int64(n)
-		return n, err
+		return u.readLive(p)
 	}
 
-	b := u.cb.Bytes()
-	n = copy(p, b[int64(len(b))-(u.bytesRead-u.cursor):])
+	n = copy(p, u.replay())
 	u.cursor += int64(n)
-	return n, nil
+	if !u.greedy || n == len(p) || u.err != nil {
+		return n, nil
+	}
+	m, err := u.readLive(p[n:])
+	if m == 0 && err == ErrWouldBlock {
+		// the next read reports it, if it still applies, so that callers
+		// checking the error first don't drop the replayed bytes
+		return n, nil
+	}
+	return n + m, err
+}
+
+// readLive reads from the underlying reader into p. It must only be called
+// when there is nothing left to replay.
+func (u *Unreader) readLive(p []byte) (n int, err error) {
+	if u.err != nil {
+		err, u.err = u.err, nil
+		return 0, err
+	}
+	max, err := u.room(len(p))
+	if err != nil {
+		return 0, err
+	}
+	n, err = u.rd.Read(p[:max])
+	u.consume(p[:n])
+	return n, err
+}
+
+// WriteTo implements io.WriterTo. Unread bytes are written first, then the
+// rest of the underlying reader is copied to w, still being recorded in the
+// buffer. Bytes that w fails to accept are left to be read again.
+func (u *Unreader) WriteTo(w io.Writer) (n int64, err error) {
+	u.clearLast()
+	if b := u.replay(); len(b) > 0 {
+		m, err := w.Write(b)
+		u.cursor += int64(m)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+		if m < len(b) {
+			return n, io.ErrShortWrite
+		}
+	}
+
+	if u.err != nil {
+		err, u.err = u.err, nil
+		if err == io.EOF {
+			err = nil
+		}
+		return n, err
+	}
+
+	// a read larger than the buffer couldn't be kept for w to retry
+	buf := make([]byte, min(32*1024, u.cb.Size()))
+	for {
+		max, err := u.room(len(buf))
+		if err != nil {
+			return n, err
+		}
+		m, rerr := u.rd.Read(buf[:max])
+		if m > 0 {
+			kept := u.keepLive()
+			k, werr := w.Write(buf[:m])
+			n += int64(k)
+			if werr == nil && k < m {
+				werr = io.ErrShortWrite
+			}
+			if werr != nil && !kept {
+				// keep what w didn't accept so it can be read again
+				u.consume(buf[:k])
+				u.record(buf[k:m])
+			} else {
+				u.consume(buf[:m])
+			}
+			if werr != nil {
+				u.cursor = u.bytesRead - int64(m-k)
+				if rerr != nil {
+					u.err = rerr
+				}
+				return n, werr
+			}
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+// ReadAtLeast reads into p until it has read at least min bytes, replaying
+// unread bytes before using the underlying reader. Errors follow
+// io.ReadAtLeast.
+func (u *Unreader) ReadAtLeast(p []byte, min int) (n int, err error) {
+	return io.ReadAtLeast(u, p, min)
+}
+
+// ReadFull reads exactly len(p) bytes into p. Errors follow io.ReadFull.
+func (u *Unreader) ReadFull(p []byte) (n int, err error) {
+	return io.ReadFull(u, p)
 }
 
-// ReadRune reads a sing rule from the underlying reader and returns the
-// rune, size, and an error if there was one.
-func (u *unreader) ReadRune() (r rune, size int, err error) {
-	u.runePos = 0
-	for !utf8.FullRune(u.runeBuf[:u.runePos]) && u.runePos < utf8.MaxRune {
-		_, err := u.Read(u.runeBuf[u.runePos : u.runePos+1])
+// replay returns the buffered bytes between the cursor and bytesRead, which
+// will be returned by reads before the underlying reader is used again.
+func (u *Unreader) replay() []byte {
+	b := u.bytes()
+	return b[int64(len(b))-(u.bytesRead-u.cursor):]
+}
+
+// record appends bytes read from the underlying reader to the buffer.
+func (u *Unreader) record(p []byte) {
+	u.cb.Write(p)
+	u.view = nil
+	u.bytesRead += int64(len(p))
+	u.written += int64(len(p))
+	u.track(p)
+	u.mirror(p)
+}
+
+// consume accounts for bytes read from the underlying reader straight to the
+// caller, recording them if recording is on, and moves the cursor past them.
+// It must only be called when there is nothing left to replay.
+func (u *Unreader) consume(p []byte) {
+	if u.keepLive() {
+		u.record(p)
+	} else if len(p) > 0 {
+		// the buffer would no longer end at bytesRead
+		u.cb.Reset()
+		u.view = nil
+		u.bytesRead += int64(len(p))
+		u.track(p)
+		u.mirror(p)
+	}
+	u.cursor = u.bytesRead
+}
+
+// keepLive reports whether bytes read straight to the caller are recorded:
+// either recording is on, or a mark pins them.
+func (u *Unreader) keepLive() bool {
+	_, pinned := u.pin()
+	return u.recording || pinned
+}
+
+// pin returns the lowest offset held by a pushed mark, open transaction or
+// fork's cursor, which the buffer must not evict.
+func (u *Unreader) pin() (off int64, ok bool) {
+	for _, r := range u.readers {
+		if r != u && (!ok || r.cursor < off) {
+			off, ok = r.cursor, true
+		}
+		for _, m := range r.marks {
+			if !ok || m.off < off {
+				off, ok = m.off, true
+			}
+		}
+		for _, m := range r.txns {
+			if !ok || m.off < off {
+				off, ok = m.off, true
+			}
+		}
+	}
+	return off, ok
+}
+
+// room returns how many of want bytes can be read from the underlying reader
+// without evicting a pinned offset, growing the buffer to fit if it is
+// growable. It returns ErrPinned if no bytes can be read.
+func (u *Unreader) room(want int) (int, error) {
+	pin, ok := u.pin()
+	if !ok {
+		return want, nil
+	}
+	avail := pin + u.cb.Size() - u.bytesRead
+	if avail >= int64(want) {
+		return want, nil
+	}
+	if u.growable {
+		u.grow(u.bytesRead + int64(want) - pin)
+		return want, nil
+	}
+	if avail <= 0 {
+		return 0, ErrPinned
+	}
+	return int(avail), nil
+}
+
+// grow replaces the buffer with one of at least size bytes, keeping the
+// bytes it holds.
+func (u *Unreader) grow(size int64) {
+	size = max(size, 2*u.cb.Size())
+	cb, _ := circbuf.NewBuffer(size)
+	cb.Write(u.bytes())
+	u.cb = cb
+	u.view = nil
+}
+
+// bytes returns the contents of the buffer. Unlike cb.Bytes, it only copies
+// a wrapped buffer once per write.
+func (u *Unreader) bytes() []byte {
+	if u.view == nil {
+		u.view = u.cb.Bytes()
+	}
+	return u.view
+}
+
+// retained returns the number of bytes held in the buffer, which always end
+// at bytesRead.
+func (u *Unreader) retained() int64 {
+	return min(u.cb.TotalWritten(), u.cb.Size())
+}
+
+// fill reads up to max bytes from the underlying reader into the buffer
+// without moving the cursor. Callers must keep max small enough that no
+// bytes after the cursor are evicted.
+func (u *Unreader) fill(max int) (n int, err error) {
+	if u.err != nil {
+		err, u.err = u.err, nil
+		return 0, err
+	}
+	if max, err = u.room(max); err != nil {
+		return 0, err
+	}
+	if cap(u.fillBuf) < max {
+		u.fillBuf = make([]byte, max)
+	}
+	p := u.fillBuf[:max]
+	for i := 0; i < maxConsecutiveEmptyReads; i++ {
+		n, err = u.rd.Read(p)
+		if n > 0 || err != nil {
+			u.record(p[:n])
+			return n, err
+		}
+	}
+	return 0, io.ErrNoProgress
+}
+
+// Peek returns the next n bytes without advancing the cursor, reading from
+// the underlying reader as needed. The bytes stop being valid at the next
+// read. If Peek returns fewer than n bytes, it also returns an error
+// explaining why the read is short. Peeking more than the buffer size
+// returns at most a buffer's worth of bytes and ErrBufferFull.
+func (u *Unreader) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+	var err error
+	if int64(n) > u.cb.Size() {
+		if u.growable {
+			u.grow(int64(n))
+		} else {
+			n = int(u.cb.Size())
+			err = ErrBufferFull
+		}
+	}
+
+	var rerr error
+	for u.bytesRead-u.cursor < int64(n) && rerr == nil {
+		_, rerr = u.fill(n - int(u.bytesRead-u.cursor))
+	}
+	b := u.replay()
+	if len(b) < n {
+		return b, rerr
+	}
+	if rerr != nil {
+		u.err = rerr
+	}
+	return b[:n], err
+}
+
+// PeekAvailable returns the bytes already buffered after the cursor, without
+// reading from the underlying reader, so it never blocks. The bytes stop
+// being valid at the next read.
+func (u *Unreader) PeekAvailable() []byte {
+	return u.replay()
+}
+
+// Discard skips the next n bytes, returning the number of bytes discarded.
+// Unread bytes are skipped first, and any bytes pulled from the underlying
+// reader are still recorded in the buffer so they can be unread later. If
+// Discard skips fewer than n bytes, it also returns an error.
+func (u *Unreader) Discard(n int64) (discarded int64, err error) {
+	if n < 0 {
+		return 0, ErrNegativeCount
+	}
+	u.clearLast()
+	for discarded < n {
+		if u.cursor == u.bytesRead {
+			_, err = u.fill(int(min(fillSize, u.cb.Size(), n-discarded)))
+			if u.cursor == u.bytesRead {
+				return discarded, err
+			}
+			if err != nil {
+				u.err = err
+			}
+		}
+		k := min(n-discarded, u.bytesRead-u.cursor)
+		u.cursor += k
+		discarded += k
+	}
+	return discarded, nil
+}
+
+// Seek implements io.Seeker over the stream offsets the Unreader can reach.
+// Seeking backward is limited to the bytes still held in the buffer, and
+// seeking forward discards bytes from the underlying reader. io.SeekEnd is
+// not supported since the length of the stream is unknown.
+func (u *Unreader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = u.cursor + offset
+	case io.SeekEnd:
+		return u.cursor, ErrSeekEnd
+	default:
+		return u.cursor, ErrInvalidWhence
+	}
+	if abs < 0 {
+		return u.cursor, ErrNegativePosition
+	}
+	if abs < u.cursor {
+		if err := u.Unread(u.cursor - abs); err != nil {
+			return u.cursor, err
+		}
+		return u.cursor, nil
+	}
+	_, err := u.Discard(abs - u.cursor)
+	return u.cursor, err
+}
+
+// SeekTo moves the cursor to an absolute offset in the stream. Offsets
+// behind the cursor must still be held in the buffer, and offsets ahead of
+// it are reached by discarding bytes.
+func (u *Unreader) SeekTo(offset int64) error {
+	_, err := u.Seek(offset, io.SeekStart)
+	return err
+}
+
+// ReadAt implements io.ReaderAt for absolute stream offsets still held in
+// the buffer, returning ErrEvicted for older offsets. Bytes beyond those
+// already read are pulled from the underlying reader, as long as that doesn't
+// evict the cursor or off. ReadAt does not move the cursor.
+func (u *Unreader) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < u.bytesRead-u.retained() {
+		return 0, ErrEvicted
+	}
+	end := off + int64(len(p))
+	low := min(off, u.cursor)
+	for u.bytesRead < end && err == nil {
+		room := u.cb.Size() - (u.bytesRead - low)
+		if room <= 0 && u.growable {
+			u.grow(end - low)
+			continue
+		}
+		if room <= 0 {
+			err = ErrBufferFull
+			break
+		}
+		_, err = u.fill(int(min(room, end-u.bytesRead)))
+	}
+
+	if off < u.bytesRead {
+		b := u.bytes()
+		n = copy(p, b[off-(u.bytesRead-int64(len(b))):])
+	}
+	if n == len(p) {
+		if err != nil {
+			u.err = err
+		}
+		return n, nil
+	}
+	return n, err
+}
+
+// ReadByte reads and returns a single byte, replaying unread bytes first.
+func (u *Unreader) ReadByte() (byte, error) {
+	u.clearLast()
+	if u.cursor == u.bytesRead {
+		n, err := u.fill(min(fillSize, int(u.cb.Size())))
+		if n == 0 {
+			return 0, err
+		}
 		if err != nil {
-			return 0, 0, err
+			u.err = err
 		}
-		u.runePos += 1
 	}
-	r, size = utf8.DecodeRune(u.runeBuf[:u.runePos+1])
+	c := u.replay()[0]
+	u.cursor++
+	return c, nil
+}
+
+// UnreadByte unreads the last byte. Unlike bufio.Reader, any byte still held
+// in the buffer can be unread, not only the one returned by the last ReadByte.
+func (u *Unreader) UnreadByte() error {
+	return u.Unread(1)
+}
+
+// ReadRune reads a single rune and returns the rune, its size, and an error
+// if there was one. The rune is decoded in place from the buffer, so bytes
+// split across reads of the underlying reader are handled without copying.
+// Invalid input is returned as utf8.RuneError with the size of the bad
+// bytes, or with strict rune decoding, as ErrInvalidRune without consuming
+// them. A stream that ends partway through a rune returns
+// io.ErrUnexpectedEOF, leaving the partial rune unread.
+func (u *Unreader) ReadRune() (r rune, size int, err error) {
+	r, size, err = u.PeekRune()
+	if err != nil {
+		if err == ErrInvalidRune {
+			return r, size, err
+		}
+		return 0, 0, err
+	}
+	u.cursor += int64(size)
+	u.clearLast()
+	u.lastRuneSize = size
+	return r, size, nil
+}
+
+// PeekRune decodes the next rune without advancing the cursor. The rune's
+// bytes may be split between the buffer and the underlying reader, which is
+// only read until the rune is complete. Invalid input is handled as by
+// ReadRune.
+func (u *Unreader) PeekRune() (r rune, size int, err error) {
+	enc := u.runeEncoding()
+	b := u.replay()
+	// a buffer smaller than a rune decodes what it can hold
+	for !fullRune(b, enc) && int64(len(b)) < u.cb.Size() && err == nil {
+		_, err = u.fill(min(fillSize, int(u.cb.Size())-len(b)))
+		b = u.replay()
+	}
+	if err != nil && !fullRune(b, enc) {
+		if err == io.EOF && len(b) > 0 {
+			// keep the EOF for reads after the partial rune
+			u.err = err
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	if err != nil {
+		u.err = err
+	}
+
+	r, size, ok := decodeRune(b, enc)
+	if !ok && u.strictRunes {
+		return r, size, ErrInvalidRune
+	}
 	return r, size, nil
 }
 
+// UnreadRune unreads the last rune. It follows the io.RuneScanner contract
+// and returns ErrInvalidUnreadRune if the last operation was not a successful
+// ReadRune.
+func (u *Unreader) UnreadRune() error {
+	if u.lastRuneSize <= 0 {
+		return ErrInvalidUnreadRune
+	}
+	return u.Unread(int64(u.lastRuneSize))
+}
+
+// UnreadRunes unreads the last n runes, decoding them backwards from the
+// buffer to find their sizes. Invalid UTF-8 counts as one rune per byte, as
+// with utf8.DecodeLastRune, and invalid UTF-16 as one rune per code unit. If
+// fewer than n runes are held before the cursor, counting a rune cut off by
+// the start of the buffer as evicted, nothing is unread and an *UnreadError
+// is returned.
+func (u *Unreader) UnreadRunes(n int) error {
+	if n < 0 {
+		return ErrNegativeCount
+	}
+	b := u.bytes()
+	hist := b[:int64(len(b))-(u.bytesRead-u.cursor)]
+	enc := u.runeEncoding()
+	size := 0
+	for i := 0; i < n; i++ {
+		if size == len(hist) {
+			err := ErrUnreadBeyondBuffer
+			if int64(size) == u.cursor {
+				err = ErrUnreadBeyondWritten
+			}
+			return u.unreadError(int64(size)+1, err)
+		}
+		_, sz := decodeLastRune(hist[:len(hist)-size], enc)
+		size += sz
+		// a rune at the start of the buffer may have lost its first bytes
+		if start := u.bytesRead - int64(len(b)); size == len(hist) && start > 0 && countRunes(hist[:1], start, enc) == 0 {
+			return u.unreadError(int64(size), ErrUnreadBeyondBuffer)
+		}
+	}
+	return u.Unread(int64(size))
+}
+
 // LastBytes returns the last n buffered bytes, preceding the current
 // cursor position.
-func (u *unreader) LastBytes(n int) []byte {
+func (u *Unreader) LastBytes(n int) []byte {
 	// unread some, then pass in the length of the match you wanted
 	b := u.Bytes()
 	l := len(b)
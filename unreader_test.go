@@ -0,0 +1,885 @@
+package unreader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// blockingReader fails the test if it is read.
+type blockingReader struct{ t *testing.T }
+
+func (r blockingReader) Read([]byte) (int, error) {
+	r.t.Fatal("underlying reader was read")
+	return 0, io.EOF
+}
+
+func TestPeekAvailable(t *testing.T) {
+	u, _ := New(blockingReader{t}, WithBufferSize(8), WithPrefill([]byte("abcdef")))
+	if b := u.PeekAvailable(); string(b) != "abcdef" {
+		t.Fatalf("PeekAvailable = %q", b)
+	}
+	u.Discard(4)
+	if b := u.PeekAvailable(); string(b) != "ef" {
+		t.Fatalf("PeekAvailable after Discard = %q", b)
+	}
+	u.Discard(2)
+	if b := u.PeekAvailable(); len(b) != 0 {
+		t.Fatalf("PeekAvailable at end of buffer = %q", b)
+	}
+	u.Unread(3)
+	if b := u.PeekAvailable(); string(b) != "def" {
+		t.Fatalf("PeekAvailable after Unread = %q", b)
+	}
+}
+
+// limitWriter accepts n bytes, then fails.
+type limitWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		w.Buffer.Write(p[:w.n])
+		k := w.n
+		w.n = 0
+		return k, io.ErrShortWrite
+	}
+	w.n -= len(p)
+	return w.Buffer.Write(p)
+}
+
+func TestWriteToShortWrite(t *testing.T) {
+	for _, recording := range []bool{true, false} {
+		var tee bytes.Buffer
+		u, _ := New(strings.NewReader("a\nb\nc\nd\n"), WithRecording(recording), WithTee(&tee))
+		w := &limitWriter{n: 2}
+		if n, err := u.WriteTo(w); n != 2 || err != io.ErrShortWrite {
+			t.Fatalf("recording %v: WriteTo = %d, %v", recording, n, err)
+		}
+		if p := u.Position(); p.Offset != 2 || p.Line != 2 || p.Column != 1 {
+			t.Errorf("recording %v: Position = %+v, want offset 2 at 2:1", recording, p)
+		}
+		if n := u.RunesRead(); n != 2 {
+			t.Errorf("recording %v: RunesRead = %d, want 2", recording, n)
+		}
+		if tee.String() != "a\nb\nc\nd\n" {
+			t.Errorf("recording %v: tee = %q", recording, tee.String())
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != "b\nc\nd\n" {
+			t.Errorf("recording %v: left %q for the next read", recording, rest)
+		}
+		if p := u.Position(); p.Line != 5 || p.Column != 1 || u.RunesRead() != 8 {
+			t.Errorf("recording %v: Position at end = %v, %d runes; want 5:1, 8 runes", recording, p, u.RunesRead())
+		}
+	}
+}
+
+func TestWriteToSmallBuffer(t *testing.T) {
+	data := strings.Repeat("0123456789", 10)
+	u, _ := NewUnreader(8, strings.NewReader(data))
+	w := &limitWriter{n: 13}
+	u.WriteTo(w)
+	if rest, _ := io.ReadAll(u); w.String()+string(rest) != data {
+		t.Fatalf("wrote %q, left %q", w.String(), rest)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	u := NewUnreaderString("hello world")
+	if n, err := u.Seek(6, io.SeekStart); n != 6 || err != nil {
+		t.Fatalf("Seek(6, SeekStart) = %d, %v", n, err)
+	}
+	if n, err := u.Seek(-2, io.SeekCurrent); n != 4 || err != nil {
+		t.Fatalf("Seek(-2, SeekCurrent) = %d, %v", n, err)
+	}
+	for _, tt := range []struct {
+		offset int64
+		whence int
+		err    error
+	}{
+		{0, io.SeekEnd, ErrSeekEnd},
+		{0, 42, ErrInvalidWhence},
+		{-5, io.SeekCurrent, ErrNegativePosition},
+	} {
+		if n, err := u.Seek(tt.offset, tt.whence); n != 4 || !errors.Is(err, tt.err) {
+			t.Errorf("Seek(%d, %d) = %d, %v, want 4, %v", tt.offset, tt.whence, n, err, tt.err)
+		}
+	}
+}
+
+func TestCloseWrapped(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"none":      WithRecording(true),
+		"transform": WithTransform(transform.Nop),
+		"newlines":  WithNewlineNormalization(true),
+		"bom":       WithBOMStripping(true),
+	} {
+		src := &closeReader{Reader: strings.NewReader("data")}
+		u, err := New(src, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Close(); err != nil || !src.closed {
+			t.Errorf("%s: Close() = %v, source closed = %v", name, err, src.closed)
+		}
+		if _, err := u.ReadByte(); err != ErrClosed {
+			t.Errorf("%s: ReadByte() after Close = %v, want ErrClosed", name, err)
+		}
+	}
+}
+
+func TestReadRune(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     io.Reader
+		strict bool
+		want   []rune
+		err    error // after the runes in want
+		rest   string
+	}{
+		{"whole", strings.NewReader("aé€😀"), false, []rune("aé€😀"), io.EOF, ""},
+		{"split across fills", iotest.OneByteReader(strings.NewReader("é€😀")), false, []rune("é€😀"), io.EOF, ""},
+		{"cut by EOF", strings.NewReader("a\xe2\x82"), false, []rune("a"), io.ErrUnexpectedEOF, "\xe2\x82"},
+		{"invalid", strings.NewReader("a\xffb"), false, []rune{'a', utf8.RuneError, 'b'}, io.EOF, ""},
+		{"strict invalid", strings.NewReader("a\xffb"), true, []rune("a"), ErrInvalidRune, "\xffb"},
+		{"strict valid", iotest.OneByteReader(strings.NewReader("€x")), true, []rune("€x"), io.EOF, ""},
+	}
+	for _, tt := range tests {
+		u, _ := New(tt.in, WithBufferSize(16), WithStrictRunes(tt.strict))
+		for _, want := range tt.want {
+			if r, _, err := u.ReadRune(); r != want || err != nil {
+				t.Fatalf("%s: ReadRune() = %q, %v, want %q", tt.name, r, err, want)
+			}
+		}
+		if r, _, err := u.ReadRune(); err != tt.err {
+			t.Errorf("%s: ReadRune() at end = %q, %v, want %v", tt.name, r, err, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q unread, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestUnread(t *testing.T) {
+	tests := []struct {
+		name   string
+		read   int
+		unread int64
+		rest   string
+	}{
+		{"nothing", 0, 0, "0123456789"},
+		{"all read", 4, 4, "0123456789"},
+		{"part", 4, 2, "23456789"},
+		{"after eviction", 10, 4, "6789"},
+	}
+	for _, tt := range tests {
+		// an exported type can be held in other packages' structs
+		var s struct{ *Unreader }
+		s.Unreader, _ = NewUnreader(4, strings.NewReader("0123456789"))
+		s.Discard(int64(tt.read))
+		if err := s.Unread(tt.unread); err != nil {
+			t.Fatalf("%s: Unread(%d) = %v", tt.name, tt.unread, err)
+		}
+		if rest, _ := io.ReadAll(s); string(rest) != tt.rest {
+			t.Errorf("%s: read %q after Unread, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	tests := []struct {
+		name string
+		in   io.Reader
+		n    int
+		want string
+		err  error
+	}{
+		{"whole", strings.NewReader("hello world"), 5, "hello", nil},
+		{"over fills", iotest.OneByteReader(strings.NewReader("hello world")), 8, "hello wo", nil},
+		{"short", strings.NewReader("hi"), 5, "hi", io.EOF},
+		{"zero", blockingReader{t}, 0, "", nil},
+		{"past buffer", strings.NewReader("hello world"), 9, "hello wo", ErrBufferFull},
+		{"negative", blockingReader{t}, -1, "", ErrNegativeCount},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(8, tt.in)
+		b, err := u.Peek(tt.n)
+		if string(b) != tt.want || err != tt.err {
+			t.Errorf("%s: Peek(%d) = %q, %v, want %q, %v", tt.name, tt.n, b, err, tt.want, tt.err)
+		}
+		if u.Cursor() != 0 {
+			t.Errorf("%s: Peek moved the cursor to %d", tt.name, u.Cursor())
+		}
+		if rest, _ := u.Peek(len(tt.want)); string(rest) != tt.want {
+			t.Errorf("%s: peeked %q again, want %q", tt.name, rest, tt.want)
+		}
+	}
+}
+
+func TestReadByte(t *testing.T) {
+	u, _ := NewUnreader(4, iotest.DataErrReader(strings.NewReader("ab")))
+	var _ io.ByteScanner = u
+	if err := u.UnreadByte(); !errors.Is(err, ErrUnreadBeyondWritten) {
+		t.Fatalf("UnreadByte() at start = %v, want ErrUnreadBeyondWritten", err)
+	}
+	for _, step := range []struct {
+		want   byte
+		unread bool
+	}{{'a', true}, {'a', false}, {'b', false}} {
+		c, err := u.ReadByte()
+		if c != step.want || err != nil {
+			t.Fatalf("ReadByte() = %q, %v, want %q", c, err, step.want)
+		}
+		if step.unread {
+			if err := u.UnreadByte(); err != nil {
+				t.Fatalf("UnreadByte() = %v", err)
+			}
+		}
+	}
+	if _, err := u.ReadByte(); err != io.EOF {
+		t.Fatalf("ReadByte() at end = %v, want io.EOF", err)
+	}
+
+	// stdlib readers that take an io.ByteReader
+	u = NewUnreaderBytes(binary.AppendUvarint(nil, 300))
+	if v, err := binary.ReadUvarint(u); v != 300 || err != nil {
+		t.Fatalf("binary.ReadUvarint() = %d, %v", v, err)
+	}
+}
+
+func TestUnreadRune(t *testing.T) {
+	tests := []struct {
+		name   string
+		before func(u *Unreader)
+		err    error
+		rest   string
+	}{
+		{"after ReadRune", func(u *Unreader) { u.ReadRune() }, nil, "héllo"},
+		{"after two", func(u *Unreader) { u.ReadRune(); u.ReadRune() }, nil, "éllo"},
+		{"twice", func(u *Unreader) { u.ReadRune(); u.UnreadRune() }, ErrInvalidUnreadRune, "héllo"},
+		{"after ReadByte", func(u *Unreader) { u.ReadByte() }, ErrInvalidUnreadRune, "éllo"},
+		{"after Discard", func(u *Unreader) { u.ReadRune(); u.Discard(2) }, ErrInvalidUnreadRune, "llo"},
+		{"at start", func(u *Unreader) {}, ErrInvalidUnreadRune, "héllo"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader("héllo"))
+		var _ io.RuneScanner = u
+		tt.before(u)
+		if err := u.UnreadRune(); err != tt.err {
+			t.Errorf("%s: UnreadRune() = %v, want %v", tt.name, err, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q unread, want %q", tt.name, rest, tt.rest)
+		}
+	}
+
+	// fmt.Fscan needs UnreadRune to leave the delimiter unread
+	u := NewUnreaderString("42 rest")
+	var n int
+	if _, err := fmt.Fscan(u, &n); n != 42 || err != nil {
+		t.Fatalf("fmt.Fscan() = %d, %v", n, err)
+	}
+	if rest, _ := io.ReadAll(u); string(rest) != " rest" {
+		t.Fatalf("left %q after fmt.Fscan", rest)
+	}
+}
+
+func TestPeekRune(t *testing.T) {
+	tests := []struct {
+		name string
+		in   io.Reader
+		skip int // bytes read before peeking
+		r    rune
+		size int
+		err  error
+	}{
+		{"ascii", strings.NewReader("a€"), 0, 'a', 1, nil},
+		{"multibyte", strings.NewReader("a€"), 1, '€', 3, nil},
+		{"split across fills", iotest.OneByteReader(strings.NewReader("a€")), 1, '€', 3, nil},
+		{"straddles replay", iotest.OneByteReader(strings.NewReader("a€")), -2, '€', 3, nil},
+		{"at EOF", strings.NewReader("a€"), 4, 0, 0, io.EOF},
+		{"cut by EOF", strings.NewReader("a\xe2\x82"), 1, 0, 0, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, tt.in)
+		if tt.skip < 0 {
+			// the rune starts in the replayed bytes and ends in live ones
+			u.Discard(3)
+			u.Unread(int64(-tt.skip))
+		} else {
+			u.Discard(int64(tt.skip))
+		}
+		at := u.Cursor()
+		r, size, err := u.PeekRune()
+		if r != tt.r || size != tt.size || err != tt.err {
+			t.Errorf("%s: PeekRune() = %q, %d, %v, want %q, %d, %v", tt.name, r, size, err, tt.r, tt.size, tt.err)
+		}
+		if u.Cursor() != at {
+			t.Errorf("%s: PeekRune moved the cursor from %d to %d", tt.name, at, u.Cursor())
+		}
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	tests := []struct {
+		name   string
+		buffer int64 // bytes unread before discarding
+		n      int64
+		want   int64
+		err    error
+		rest   string
+	}{
+		{"live", 0, 3, 3, nil, "3456789"},
+		{"replayed", 4, 2, 2, nil, "23456789"},
+		{"replayed then live", 4, 7, 7, nil, "789"},
+		{"past EOF", 0, 12, 10, io.EOF, ""},
+		{"zero", 0, 0, 0, nil, "0123456789"},
+		{"negative", 0, -1, 0, ErrNegativeCount, "0123456789"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, iotest.OneByteReader(strings.NewReader("0123456789")))
+		if tt.buffer > 0 {
+			u.Discard(tt.buffer)
+			u.Unread(tt.buffer)
+		}
+		if n, err := u.Discard(tt.n); n != tt.want || err != tt.err {
+			t.Errorf("%s: Discard(%d) = %d, %v, want %d, %v", tt.name, tt.n, n, err, tt.want, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+
+	// discarded bytes are recorded like read ones
+	u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+	u.Discard(7)
+	if err := u.Unread(4); err != nil {
+		t.Fatalf("Unread(4) after Discard = %v", err)
+	}
+	if rest, _ := io.ReadAll(u); string(rest) != "3456789" {
+		t.Fatalf("left %q after Unread", rest)
+	}
+}
+
+func TestSeekBuffered(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int64
+		want   int64
+		err    error
+	}{
+		{"back within buffer", 3, 3, nil},
+		{"forward", 8, 8, nil},
+		{"past EOF", 12, 10, io.EOF},
+		{"evicted", 1, 6, ErrUnreadBeyondBuffer},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+		u.Discard(6)
+		n, err := u.Seek(tt.offset, io.SeekStart)
+		if n != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("%s: Seek(%d) = %d, %v, want %d, %v", tt.name, tt.offset, n, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		discard int64
+		off     int64
+		want    string
+		err     error
+	}{
+		{"ahead of cursor", 0, 2, "234", nil},
+		{"past the buffer", 0, 4, "45", ErrBufferFull},
+		{"behind cursor", 8, 7, "789", nil},
+		{"at EOF", 10, 8, "89", io.EOF},
+		{"evicted", 10, 1, "", ErrEvicted},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(6, iotest.OneByteReader(strings.NewReader("0123456789")))
+		var _ io.ReaderAt = u
+		u.Discard(tt.discard)
+		p := make([]byte, 3)
+		n, err := u.ReadAt(p, tt.off)
+		if string(p[:n]) != tt.want || err != tt.err {
+			t.Errorf("%s: ReadAt(%d) = %q, %v, want %q, %v", tt.name, tt.off, p[:n], err, tt.want, tt.err)
+		}
+		if u.Cursor() != tt.discard {
+			t.Errorf("%s: ReadAt moved the cursor to %d", tt.name, u.Cursor())
+		}
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	tests := []struct {
+		name   string
+		unread int64
+		want   string
+	}{
+		{"live", 0, "0123456789"},
+		{"replayed then live", 3, "3456789"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+		var _ io.WriterTo = u
+		if tt.unread > 0 {
+			u.Discard(6)
+			u.Unread(tt.unread)
+		}
+		var w strings.Builder
+		n, err := io.Copy(&w, u)
+		if w.String() != tt.want || n != int64(len(tt.want)) || err != nil {
+			t.Errorf("%s: io.Copy() = %d, %v, wrote %q, want %q", tt.name, n, err, w.String(), tt.want)
+		}
+		// the tail is still recorded for a rewind
+		if err := u.Unread(4); err != nil {
+			t.Errorf("%s: Unread(4) after WriteTo = %v", tt.name, err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != "6789" {
+			t.Errorf("%s: left %q after Unread", tt.name, rest)
+		}
+	}
+}
+
+func TestBuffered(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(u *Unreader)
+		want int64
+	}{
+		{"fresh", func(u *Unreader) {}, 0},
+		{"after Read", func(u *Unreader) { u.Discard(3) }, 0},
+		{"after Unread", func(u *Unreader) { u.Discard(3); u.Unread(2) }, 2},
+		{"after Peek", func(u *Unreader) { u.Peek(5) }, 5},
+		{"partly replayed", func(u *Unreader) { u.Peek(5); u.Discard(2) }, 3},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(8, iotest.OneByteReader(strings.NewReader("0123456789")))
+		tt.ops(u)
+		if n := u.Buffered(); n != tt.want {
+			t.Errorf("%s: Buffered() = %d, want %d", tt.name, n, tt.want)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(u *Unreader)
+	}{
+		{"after reads", func(u *Unreader) { u.Discard(6) }},
+		{"with replay pending", func(u *Unreader) { u.Discard(6); u.Unread(3) }},
+		{"at EOF", func(u *Unreader) { io.ReadAll(u) }},
+		{"inside a mark", func(u *Unreader) { u.PushMark("m"); u.Discard(2) }},
+		{"inside a transaction", func(u *Unreader) { u.Begin(); u.Discard(2) }},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(8, strings.NewReader("0123456789"))
+		tt.ops(u)
+		u.Reset(strings.NewReader("abc"))
+		if u.Cursor() != 0 || u.BytesRead() != 0 || u.TotalWritten() != 0 || u.Len() != 0 {
+			t.Errorf("%s: after Reset cursor %d, read %d, written %d, len %d", tt.name, u.Cursor(), u.BytesRead(), u.TotalWritten(), u.Len())
+		}
+		if _, err := u.PopMark(); err != ErrNoMark || u.InTransaction() != 0 {
+			t.Errorf("%s: marks or transactions kept across Reset", tt.name)
+		}
+		if b, err := io.ReadAll(u); string(b) != "abc" || err != nil {
+			t.Errorf("%s: read %q, %v after Reset", tt.name, b, err)
+		}
+		if err := u.Unread(4); !errors.Is(err, ErrUnreadBeyondWritten) {
+			t.Errorf("%s: Unread before the new stream = %v", tt.name, err)
+		}
+	}
+}
+
+func TestNewUnreaderBytes(t *testing.T) {
+	for _, in := range []string{"", "a", "hello world"} {
+		u := NewUnreaderString(in)
+		if u.Buffered() != int64(len(in)) {
+			t.Errorf("%q: Buffered() = %d before any read", in, u.Buffered())
+		}
+		if b, err := io.ReadAll(u); string(b) != in || err != nil {
+			t.Errorf("%q: read %q, %v", in, b, err)
+		}
+		if err := u.Unread(int64(len(in))); err != nil {
+			t.Errorf("%q: Unread(%d) = %v", in, len(in), err)
+		}
+		if b, _ := io.ReadAll(NewUnreaderBytes([]byte(in))); string(b) != in {
+			t.Errorf("%q: NewUnreaderBytes read %q", in, b)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	tests := []struct {
+		name string
+		src  io.Reader
+	}{
+		{"closer", &closeReader{Reader: strings.NewReader("abcdef")}},
+		{"not a closer", strings.NewReader("abcdef")},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(8, tt.src)
+		var _ io.ReadCloser = u
+		u.Peek(4)
+		u.Discard(2)
+		if err := u.Close(); err != nil {
+			t.Fatalf("%s: Close() = %v", tt.name, err)
+		}
+		if c, ok := tt.src.(*closeReader); ok && !c.closed {
+			t.Errorf("%s: source not closed", tt.name)
+		}
+		// buffered bytes are released along with the source
+		if n, err := u.Read(make([]byte, 4)); n != 0 || err != ErrClosed {
+			t.Errorf("%s: Read() after Close = %d, %v, want ErrClosed", tt.name, n, err)
+		}
+		if err := u.Close(); err != nil {
+			t.Errorf("%s: second Close() = %v", tt.name, err)
+		}
+	}
+}
+
+func TestUnreadErrorSentinels(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int64
+		read   int64
+		unread int64
+		err    error
+	}{
+		{"within buffer", 4, 6, 4, nil},
+		{"past start of stream", 8, 2, 3, ErrUnreadBeyondWritten},
+		{"past start of buffer", 4, 6, 5, ErrUnreadBeyondBuffer},
+		{"negative", 4, 2, -1, ErrNegativeCount},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(tt.size, strings.NewReader("0123456789"))
+		u.Discard(tt.read)
+		if err := u.Unread(tt.unread); !errors.Is(err, tt.err) {
+			t.Errorf("%s: Unread(%d) = %v, want %v", tt.name, tt.unread, err, tt.err)
+		}
+	}
+}
+
+func TestUnreadError(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int64
+		read    int64
+		unread  int64 // before the failing one
+		request int64
+		err     error
+		max     int64
+	}{
+		{"past start of stream", 8, 2, 0, 3, ErrUnreadBeyondWritten, 2},
+		{"past start of buffer", 4, 6, 0, 5, ErrUnreadBeyondBuffer, 4},
+		{"with replay pending", 4, 6, 2, 3, ErrUnreadBeyondBuffer, 2},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(tt.size, strings.NewReader("0123456789"))
+		u.Discard(tt.read)
+		u.Unread(tt.unread)
+		err := u.Unread(tt.request)
+		var ue *UnreadError
+		if !errors.As(err, &ue) || !errors.Is(err, tt.err) {
+			t.Fatalf("%s: Unread(%d) = %v, want an *UnreadError wrapping %v", tt.name, tt.request, err, tt.err)
+		}
+		if ue.Requested != tt.request || ue.Max != tt.max || ue.Size != tt.size {
+			t.Errorf("%s: UnreadError = %+v, want max %d", tt.name, ue, tt.max)
+		}
+		// rewinding as far as reported works
+		if err := u.Unread(ue.Max); err != nil {
+			t.Errorf("%s: Unread(Max) = %v", tt.name, err)
+		}
+	}
+}
+
+func TestUnreadAll(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(u *Unreader)
+		want int64
+		rest string
+	}{
+		{"fresh", func(u *Unreader) {}, 0, "0123456789"},
+		{"within buffer", func(u *Unreader) { u.Discard(3) }, 3, "0123456789"},
+		{"after eviction", func(u *Unreader) { u.Discard(6) }, 4, "23456789"},
+		{"with replay pending", func(u *Unreader) { u.Discard(6); u.Peek(1) }, 3, "3456789"},
+		{"already rewound", func(u *Unreader) { u.Discard(6); u.UnreadAll() }, 0, "23456789"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+		tt.ops(u)
+		if n := u.UnreadAll(); n != tt.want {
+			t.Errorf("%s: UnreadAll() = %d, want %d", tt.name, n, tt.want)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestSeekTo(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int64
+		err    error
+		cursor int64
+	}{
+		{"current", 6, nil, 6},
+		{"back", 2, nil, 2},
+		{"forward", 9, nil, 9},
+		{"end", 10, nil, 10},
+		{"past EOF", 11, io.EOF, 10},
+		{"evicted", 1, ErrUnreadBeyondBuffer, 6},
+		{"negative", -1, ErrNegativePosition, 6},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+		u.Discard(6)
+		if err := u.SeekTo(tt.offset); !errors.Is(err, tt.err) || u.Cursor() != tt.cursor {
+			t.Errorf("%s: SeekTo(%d) = %v, cursor %d, want %v, %d", tt.name, tt.offset, err, u.Cursor(), tt.err, tt.cursor)
+		}
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int64
+		err  error
+		rest string
+	}{
+		{"none", 0, nil, "3456789"},
+		{"part", 2, nil, "56789"},
+		{"all", 3, nil, "6789"},
+		{"past buffered", 4, ErrAdvanceBeyondBuffered, "3456789"},
+		{"negative", -1, ErrNegativeCount, "3456789"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+		u.Discard(6)
+		u.Unread(3)
+		if err := u.Advance(tt.n); err != tt.err {
+			t.Errorf("%s: Advance(%d) = %v, want %v", tt.name, tt.n, err, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+}
+
+func TestReadFull(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		min  int
+		want string
+		err  error
+	}{
+		{"full", 6, 6, "234567", nil},
+		{"at least", 6, 3, "234", nil},
+		{"short", 10, 10, "23456789", io.ErrUnexpectedEOF},
+		{"min past p", 2, 3, "", io.ErrShortBuffer},
+	}
+	for _, tt := range tests {
+		// a Read that replays returns only the replayed bytes, so the
+		// loop continues from the underlying reader
+		u, _ := NewUnreader(4, iotest.OneByteReader(strings.NewReader("0123456789")))
+		u.Discard(4)
+		u.Unread(2)
+		p := make([]byte, tt.size)
+		var n int
+		var err error
+		if tt.min == tt.size {
+			n, err = u.ReadFull(p)
+		} else {
+			n, err = u.ReadAtLeast(p, tt.min)
+		}
+		if string(p[:n]) != tt.want || err != tt.err {
+			t.Errorf("%s: read %q, %v, want %q, %v", tt.name, p[:n], err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestMaxUnread(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(u *Unreader)
+		want int64
+	}{
+		{"fresh", func(u *Unreader) {}, 0},
+		{"within buffer", func(u *Unreader) { u.Discard(3) }, 3},
+		{"after eviction", func(u *Unreader) { u.Discard(6) }, 4},
+		{"after Unread", func(u *Unreader) { u.Discard(6); u.Unread(1) }, 3},
+		{"after Peek", func(u *Unreader) { u.Discard(6); u.Peek(2) }, 2},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+		tt.ops(u)
+		n := u.MaxUnread()
+		if n != tt.want {
+			t.Errorf("%s: MaxUnread() = %d, want %d", tt.name, n, tt.want)
+		}
+		if err := u.Unread(n + 1); err == nil {
+			t.Errorf("%s: Unread(%d) past MaxUnread succeeded", tt.name, n+1)
+		}
+		if err := u.Unread(n); err != nil {
+			t.Errorf("%s: Unread(%d) = %v", tt.name, n, err)
+		}
+	}
+}
+
+func TestGreedyRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		greedy bool
+		size   int
+		want   string
+	}{
+		{"off", false, 6, "01"},
+		{"on", true, 6, "012345"},
+		{"on, capped by the buffer", true, 12, "01234567"},
+		{"on, p within replay", true, 1, "0"},
+	}
+	for _, tt := range tests {
+		u, _ := New(strings.NewReader("0123456789"), WithBufferSize(8), WithGreedyRead(tt.greedy))
+		u.Peek(2)
+		p := make([]byte, tt.size)
+		if n, err := u.Read(p); string(p[:n]) != tt.want || err != nil {
+			t.Errorf("%s: Read() = %q, %v, want %q", tt.name, p[:n], err, tt.want)
+		}
+		// everything returned is still recorded
+		if n := u.UnreadAll(); n != int64(len(tt.want)) {
+			t.Errorf("%s: UnreadAll() = %d after Read", tt.name, n)
+		}
+	}
+}
+
+func TestReadLargerThanBuffer(t *testing.T) {
+	for _, greedy := range []bool{false, true} {
+		u, _ := New(strings.NewReader("0123456789"), WithBufferSize(4), WithGreedyRead(greedy))
+		u.Peek(2)
+		p := make([]byte, 10)
+		n, err := u.Read(p)
+		if n > 4 || err != nil {
+			t.Fatalf("greedy %v: Read() = %d, %v, want at most the buffer size", greedy, n, err)
+		}
+		// the whole read can be rewound
+		if err := u.Unread(int64(n)); err != nil {
+			t.Errorf("greedy %v: Unread(%d) = %v", greedy, n, err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != "0123456789" {
+			t.Errorf("greedy %v: read %q after Unread", greedy, rest)
+		}
+	}
+}
+
+func TestStopRecording(t *testing.T) {
+	u, _ := NewUnreader(8, strings.NewReader("0123456789"))
+	u.Peek(4)
+	u.StopRecording()
+	tests := []struct {
+		name   string
+		read   int
+		want   string
+		unread int64
+		err    error
+	}{
+		{"buffered bytes replay", 3, "012", 2, nil},
+		{"rest of the buffer", 6, "123", 0, nil},
+		{"live bytes aren't kept", 3, "456", 1, ErrUnreadBeyondBuffer},
+	}
+	for _, tt := range tests {
+		p := make([]byte, tt.read)
+		if n, _ := u.Read(p); string(p[:n]) != tt.want {
+			t.Fatalf("%s: Read() = %q, want %q", tt.name, p[:n], tt.want)
+		}
+		if err := u.Unread(tt.unread); !errors.Is(err, tt.err) {
+			t.Fatalf("%s: Unread(%d) = %v, want %v", tt.name, tt.unread, err, tt.err)
+		}
+	}
+
+	u.StartRecording()
+	u.Discard(2)
+	if err := u.Unread(2); err != nil {
+		t.Fatalf("Unread(2) after StartRecording = %v", err)
+	}
+	if rest, _ := io.ReadAll(u); string(rest) != "789" {
+		t.Fatalf("left %q", rest)
+	}
+}
+
+func TestBufferSizes(t *testing.T) {
+	tests := []struct {
+		name               string
+		ops                func(u *Unreader)
+		size, len, written int64
+	}{
+		{"fresh", func(u *Unreader) {}, 4, 0, 0},
+		{"partly filled", func(u *Unreader) { u.Discard(3) }, 4, 3, 3},
+		{"wrapped", func(u *Unreader) { u.Discard(6) }, 4, 4, 6},
+		{"not recording", func(u *Unreader) { u.Discard(2); u.StopRecording(); u.Read(make([]byte, 3)) }, 4, 0, 2},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(4, strings.NewReader("0123456789"))
+		tt.ops(u)
+		if u.Size() != tt.size || u.Len() != tt.len || u.TotalWritten() != tt.written {
+			t.Errorf("%s: Size, Len, TotalWritten = %d, %d, %d, want %d, %d, %d",
+				tt.name, u.Size(), u.Len(), u.TotalWritten(), tt.size, tt.len, tt.written)
+		}
+		if int64(len(u.Bytes())) != u.Len() {
+			t.Errorf("%s: Len() = %d, but Bytes() holds %d", tt.name, u.Len(), len(u.Bytes()))
+		}
+	}
+}
+
+func TestUnreadRunes(t *testing.T) {
+	tests := []struct {
+		name string
+		read int // runes read from "aé€😀x"
+		n    int
+		err  error
+		rest string
+	}{
+		{"none", 3, 0, nil, "😀x"},
+		{"mixed widths", 4, 3, nil, "é€😀x"},
+		{"all", 5, 5, nil, "aé€😀x"},
+		{"past start of stream", 2, 3, ErrUnreadBeyondWritten, "€😀x"},
+		{"negative", 2, -1, ErrNegativeCount, "€😀x"},
+	}
+	for _, tt := range tests {
+		u, _ := NewUnreader(16, strings.NewReader("aé€😀x"))
+		for i := 0; i < tt.read; i++ {
+			u.ReadRune()
+		}
+		if err := u.UnreadRunes(tt.n); !errors.Is(err, tt.err) {
+			t.Errorf("%s: UnreadRunes(%d) = %v, want %v", tt.name, tt.n, err, tt.err)
+		}
+		if rest, _ := io.ReadAll(u); string(rest) != tt.rest {
+			t.Errorf("%s: left %q, want %q", tt.name, rest, tt.rest)
+		}
+	}
+
+	// runes evicted from the buffer can't be unread
+	u, _ := NewUnreader(4, strings.NewReader("€€€"))
+	u.Discard(6)
+	if err := u.UnreadRunes(2); !errors.Is(err, ErrUnreadBeyondBuffer) {
+		t.Errorf("UnreadRunes(2) past the buffer = %v, want ErrUnreadBeyondBuffer", err)
+	}
+	if err := u.UnreadRunes(1); err != nil {
+		t.Errorf("UnreadRunes(1) = %v", err)
+	}
+}